@@ -5,14 +5,20 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"math/big"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/trust-assignment/initializer"
+	"github.com/trust-assignment/internal/models"
+	repo "github.com/trust-assignment/internal/repository"
 	parser "github.com/trust-assignment/internal/service/parsersvc"
+	"github.com/trust-assignment/internal/service/scannersvc"
+	"github.com/trust-assignment/pkg/ethclient"
 )
 
 func init() {
@@ -26,10 +32,16 @@ const (
 
 	// DefaultInitialBlock will start scanning from the latest block.
 	DefaultInitialBlock = 0
+
+	// DefaultCheckpointFile is where the scanner cursor is persisted.
+	DefaultCheckpointFile = "scanner-checkpoint.json"
 )
 
 var (
-	ScanInterval = time.Second * 10 // 10 seconds
+	ScanInterval        = time.Second * 10 // 10 seconds
+	ShutdownTimeout     = time.Second * 5  // 5 seconds
+	HealthCheckInterval = time.Second * 30 // 30 seconds
+	MempoolPollInterval = time.Second * 5  // 5 seconds
 )
 
 func main() {
@@ -43,31 +55,152 @@ func run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	initialBlock := flag.Int("block", DefaultInitialBlock, "block number to start scanning from")
+	initialBlock := flag.Int("block", DefaultInitialBlock, "block number to start scanning from (deprecated, use -from)")
+	from := flag.String("from", "resume", `where to start scanning: "resume" from the checkpoint, "head", or a block number`)
+	checkpointFile := flag.String("checkpoint", DefaultCheckpointFile, "file the scan checkpoint is persisted to; empty disables it")
+	rpcURLs := flag.String("rpc", Endpoint, "comma-separated JSON-RPC endpoints, in failover priority order")
+	roundRobin := flag.Bool("round-robin", false, "spread requests over all healthy endpoints instead of failing over")
+	maxLag := flag.Int("max-lag", ethclient.DefaultMaxLag, "blocks an endpoint may trail the best head before it is skipped")
+	wsURL := flag.String("ws", "", "WebSocket endpoint; when set, scanning is driven by newHeads notifications")
+	confirmations := flag.Int("confirmations", 0, "blocks that must be mined on top of a block before it is scanned")
+	follow := flag.String("follow", ethclient.TagLatest, "block tag to scan up to: latest, safe or finalized")
+	concurrency := flag.Int("concurrency", scannersvc.DefaultConcurrency, "blocks fetched in parallel while catching up")
+	traces := flag.Bool("traces", false, "trace blocks to record ETH sent by contracts; needs debug_traceBlockByNumber or trace_block")
+	mempool := flag.String("mempool", "", `watch pending transactions: "subscribe" over -ws, or "poll" txpool_content`)
+	rateLimit := flag.Float64("rps", 0, "maximum block requests per second while catching up; 0 means unlimited")
 	flag.Parse()
 
-	service := parser.NewParser(ctx, Endpoint, *initialBlock)
-	service.Scansvc.StartScan(ScanInterval)
+	switch *follow {
+	case ethclient.TagLatest, ethclient.TagSafe, ethclient.TagFinalized:
+	default:
+		return fmt.Errorf("invalid -follow value %q", *follow)
+	}
+
+	switch *mempool {
+	case "", "poll":
+	case "subscribe":
+		if *wsURL == "" {
+			return fmt.Errorf("-mempool=subscribe needs -ws")
+		}
+	default:
+		return fmt.Errorf("invalid -mempool value %q", *mempool)
+	}
+
+	startAt, resume := DefaultInitialBlock, false
+	switch {
+	case *initialBlock != DefaultInitialBlock:
+		startAt = *initialBlock
+	case *from == "resume":
+		resume = true
+	case *from == "head":
+	default:
+		n, err := strconv.Atoi(*from)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid -from value %q", *from)
+		}
+		startAt = n
+	}
+
+	endpoints := strings.Split(*rpcURLs, ",")
+	opts := []ethclient.Option{
+		ethclient.WithEndpoints(endpoints[1:]...),
+		ethclient.WithMaxLag(*maxLag),
+	}
+	if *roundRobin {
+		opts = append(opts, ethclient.WithStrategy(ethclient.RoundRobin))
+	}
+	if *wsURL != "" {
+		opts = append(opts, ethclient.WithWebSocketURL(*wsURL))
+	}
+
+	service := parser.NewParser(ctx, endpoints[0], startAt, opts...)
+	service.Scansvc.Confirmations = *confirmations
+	service.Scansvc.Follow = *follow
+	service.Scansvc.Concurrency = *concurrency
+	service.Scansvc.Traces = *traces
+	service.Scansvc.SetRateLimit(*rateLimit)
+	if *checkpointFile != "" {
+		service.Scansvc.Checkpoints = repo.NewFileCheckpoint(*checkpointFile)
+	}
+	if resume {
+		ok, err := service.Scansvc.Resume(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("no checkpoint found, starting from the chain head")
+		}
+	}
+	if len(endpoints) > 1 {
+		service.Scansvc.Client.StartHealthChecks(ctx, HealthCheckInterval)
+	}
+	if *wsURL != "" {
+		heads, err := service.Scansvc.Client.SubscribeNewHeads(ctx)
+		if err != nil {
+			return err
+		}
+		service.Scansvc.StartHeadScan(heads, ScanInterval)
+	} else {
+		service.Scansvc.StartScan(ScanInterval)
+	}
+
+	switch *mempool {
+	case "subscribe":
+		pending, err := service.Scansvc.Client.SubscribePendingTransactions(ctx)
+		if err != nil {
+			return err
+		}
+		service.Scansvc.WatchPending(pending)
+	case "poll":
+		service.Scansvc.PollTxPool(MempoolPollInterval)
+	}
+
+	go func() {
+		for event := range service.Scansvc.Reorgs() {
+			fmt.Printf("\nreorg: %d blocks orphaned after block %d, %d transactions removed\n",
+				event.Depth, event.CommonAncestor, event.RemovedTxns)
+		}
+	}()
+
+	go func() {
+		for event := range service.Scansvc.Deployments() {
+			via := "deployed"
+			if event.Factory {
+				via = "created by factory"
+			}
+			fmt.Printf("\ncontract %s %s %s in block %d (tx %s)\n",
+				event.Contract, via, event.Deployer, event.BlockNumber, event.TxHash)
+		}
+	}()
 
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	{
+	lines := make(chan string)
+	go func() {
 		reader := bufio.NewReader(os.Stdin)
+		for {
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				close(lines)
+				return
+			}
+			lines <- input
+		}
+	}()
 
+	{
 	Exit:
 		for {
+			fmt.Print("> ")
 			select {
 			case sig := <-shutdown:
 				fmt.Println("shutdown started - received signal: ", sig)
-				cancel()
 				break Exit
-			default:
-				fmt.Print("> ")
-				input, err := reader.ReadString('\n')
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-					continue
+			case input, ok := <-lines:
+				if !ok {
+					break Exit
 				}
 
 				input = strings.TrimSuffix(input, "\n")
@@ -90,6 +223,22 @@ func run() error {
 
 				if operation == "stats" {
 					fmt.Println("Current block:", service.Scansvc.GetCurrentBlock())
+					printEndpoints(service.Scansvc.Client.Endpoints())
+					printBackfills(service.Scansvc.Backfills())
+					fmt.Println()
+					continue
+				}
+
+				if operation == "subscriptions" {
+					subs, err := service.ListSubscriptions()
+					if err != nil {
+						fmt.Fprintln(os.Stderr, "failed to list subscriptions:", err)
+						continue
+					}
+					fmt.Println("Subscriptions:")
+					for _, sub := range subs {
+						printSubscription(sub)
+					}
 					fmt.Println()
 					continue
 				}
@@ -99,20 +248,171 @@ func run() error {
 					continue
 				}
 
+				parsed, err := models.ParseAddress(args[1])
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					continue
+				}
+				address := parsed.Hex()
+
 				switch operation {
 				case "subscribe":
-					address := args[1]
-					if ok := service.Subscribe(address); !ok {
-						fmt.Fprintln(os.Stderr, err)
+					fromBlock := 0
+					if len(args) > 2 {
+						n, err := strconv.Atoi(args[2])
+						if err != nil || n <= 0 {
+							fmt.Fprintf(os.Stderr, "invalid start block [%s]\n", args[2])
+							continue
+						}
+						fromBlock = n
+					}
+					if err := service.SubscribeFrom(address, fromBlock); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to subscribe address [%s]: %v\n", address, err)
+						continue
 					}
 					fmt.Printf("Address [%s] subscribed successfully\n", address)
 					fmt.Println()
+				case "unsubscribe":
+					if err := service.Unsubscribe(address); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to unsubscribe address [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Printf("Address [%s] unsubscribed\n", address)
+					fmt.Println()
+				case "subscription":
+					sub, err := service.GetSubscription(address)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get subscription [%s]: %v\n", address, err)
+						continue
+					}
+					printSubscription(sub)
+					fmt.Println()
 				case "transactions":
-					address := args[1]
-					txs := service.GetTransactions(address)
+					if len(args) > 2 {
+						// Transactions since the given date or time, a page
+						// at a time.
+						since, err := parseTime(args[2])
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "invalid time [%s], use YYYY-MM-DD or RFC 3339\n", args[2])
+							continue
+						}
+						page, err := service.QueryTransactions(address, models.TxQuery{Since: since})
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "failed to get transactions [%s]: %v\n", address, err)
+							continue
+						}
+						fmt.Println("Transactions:")
+						for _, tx := range page.Transactions {
+							printTransaction(tx)
+						}
+						if page.NextCursor != "" {
+							fmt.Printf("Next page: query %s since=%s cursor=%s\n", address, args[2], page.NextCursor)
+						}
+						fmt.Println()
+						continue
+					}
+					txs, err := service.GetTransactions(address)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get transactions [%s]: %v\n", address, err)
+						continue
+					}
 					fmt.Println("Transactions:")
 					for _, tx := range txs {
-						fmt.Printf("%+v\n", tx)
+						printTransaction(tx)
+					}
+					fmt.Println()
+				case "query":
+					q, err := parseQuery(args[2:])
+					if err != nil {
+						fmt.Fprintln(os.Stderr, err)
+						continue
+					}
+					page, err := service.QueryTransactions(address, q)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to query transactions [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Println("Transactions:")
+					for _, tx := range page.Transactions {
+						printTransaction(tx)
+					}
+					if page.NextCursor != "" {
+						fmt.Println("Next page: cursor=" + page.NextCursor)
+					}
+					fmt.Println()
+				case "tokens":
+					transfers, err := service.GetTokenTransfers(address)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get token transfers [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Println("Token transfers:")
+					for _, t := range transfers {
+						fmt.Printf("  %s #%d block=%v token=%s %s counterparty=%s amount=%v %s\n",
+							t.TxHash, t.LogIndex, t.BlockNumber, t.Token, t.Direction, t.Counterparty, t.Amount, t.Confirmation)
+					}
+					fmt.Println()
+				case "internal":
+					internal, err := service.GetInternalTransfers(address)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get internal transfers [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Println("Internal transfers:")
+					for _, t := range internal {
+						fmt.Printf("  %s [%s] block=%v %s %s counterparty=%s value=%v %s\n",
+							t.TxHash, t.TraceAddress, t.BlockNumber, t.Type, t.Direction, t.Counterparty, t.Value, t.Confirmation)
+					}
+					fmt.Println()
+				case "pending":
+					pending, err := service.GetPending(address)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get pending transactions [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Println("Pending transactions:")
+					for _, p := range pending {
+						fmt.Printf("  %s %s from=%s to=%s value=%v nonce=%v %s",
+							p.FirstSeen.Format(time.RFC3339), p.Hash, p.From, p.To, p.Value, p.Nonce, p.State)
+						if p.ReplacedBy != "" {
+							fmt.Printf(" by %s", p.ReplacedBy)
+						}
+						fmt.Println()
+					}
+					fmt.Println()
+				case "withdrawals":
+					withdrawals, err := service.GetWithdrawals(address)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get withdrawals [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Println("Withdrawals:")
+					for _, w := range withdrawals {
+						fmt.Printf("  %s #%d block=%v validator=%d amount=%v wei %s\n",
+							w.Timestamp.Format(time.RFC3339), w.Index, w.BlockNumber, w.ValidatorIndex, w.Amount, w.Confirmation)
+					}
+					fmt.Println()
+				case "nfts":
+					var nfts []models.NFTTransfer
+					if len(args) > 3 {
+						from, err1 := strconv.Atoi(args[2])
+						to, err2 := strconv.Atoi(args[3])
+						if err1 != nil || err2 != nil || from > to {
+							fmt.Fprintf(os.Stderr, "invalid block range [%s..%s]\n", args[2], args[3])
+							continue
+						}
+						nfts, err = service.NFTsReceived(address, from, to)
+					} else {
+						nfts, err = service.GetNFTTransfers(address)
+					}
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed to get NFT transfers [%s]: %v\n", address, err)
+						continue
+					}
+					fmt.Println("NFT transfers:")
+					for _, n := range nfts {
+						fmt.Printf("  %s #%d block=%v %s %s id=%v amount=%v %s counterparty=%s %s\n",
+							n.TxHash, n.LogIndex, n.BlockNumber, n.Standard, n.Contract, n.TokenID, n.Amount, n.Direction, n.Counterparty, n.Confirmation)
 					}
 					fmt.Println()
 				}
@@ -120,8 +420,14 @@ func run() error {
 		}
 	}
 
-	ticker := time.NewTicker(time.Second * 2)
-	<-ticker.C
+	// Cancelling the context aborts any in-flight RPC request, so the
+	// scanner should stop promptly; ShutdownTimeout is only a safety net.
+	cancel()
+	select {
+	case <-service.Scansvc.Done():
+	case <-time.After(ShutdownTimeout):
+		fmt.Println("shutdown timed out waiting for scanner")
+	}
 	fmt.Println("shutdown completed")
 
 	return nil
@@ -130,10 +436,162 @@ func run() error {
 func help() {
 	fmt.Println("Usage: <operation> <input>")
 	fmt.Println("Available commands:")
-	fmt.Println("  subscribe <ethereum_address>")
-	fmt.Println("  transactions <ethereum_address>")
+	fmt.Println("  subscribe <ethereum_address> [from_block]")
+	fmt.Println("  unsubscribe <ethereum_address>")
+	fmt.Println("  subscription <ethereum_address>")
+	fmt.Println("  subscriptions")
+	fmt.Println("  transactions <ethereum_address> [since]")
+	fmt.Println("  query <ethereum_address> [dir=in|out|self] [from=block] [to=block] [since=time] [until=time]")
+	fmt.Println("        [min=wei] [max=wei] [status=success|failed] [order=asc|desc] [limit=n] [cursor=c]")
+	fmt.Println("  tokens <ethereum_address>")
+	fmt.Println("  nfts <ethereum_address> [from_block to_block]")
+	fmt.Println("  internal <ethereum_address>")
+	fmt.Println("  withdrawals <ethereum_address>")
+	fmt.Println("  pending <ethereum_address>")
 	fmt.Println("  stats")
 	fmt.Println("  exit")
 	fmt.Println("  help")
 	fmt.Println()
 }
+
+// parseTime accepts a date (YYYY-MM-DD, UTC) or an RFC 3339 time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseQuery builds a transaction query from key=value arguments.
+func parseQuery(args []string) (models.TxQuery, error) {
+	var q models.TxQuery
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return q, fmt.Errorf("invalid query argument [%s], expected key=value", arg)
+		}
+		var err error
+		switch key {
+		case "dir":
+			switch value {
+			case "in":
+				q.Direction = models.DirectionIn
+			case "out":
+				q.Direction = models.DirectionOut
+			case "self":
+				q.Direction = models.DirectionSelf
+			default:
+				err = fmt.Errorf("expected in, out or self")
+			}
+		case "from":
+			q.FromBlock, err = strconv.Atoi(value)
+		case "to":
+			q.ToBlock, err = strconv.Atoi(value)
+		case "since":
+			q.Since, err = parseTime(value)
+		case "until":
+			q.Until, err = parseTime(value)
+		case "min", "max":
+			n, ok := new(big.Int).SetString(value, 10)
+			if !ok {
+				err = fmt.Errorf("expected an amount in wei")
+			} else if key == "min" {
+				q.MinValue = n
+			} else {
+				q.MaxValue = n
+			}
+		case "status":
+			status := models.TxStatusSuccess
+			switch value {
+			case "success":
+			case "failed":
+				status = models.TxStatusFailed
+			default:
+				err = fmt.Errorf("expected success or failed")
+			}
+			q.Status = &status
+		case "order":
+			switch value {
+			case "asc":
+				q.Order = models.Ascending
+			case "desc":
+				q.Order = models.Descending
+			default:
+				err = fmt.Errorf("expected asc or desc")
+			}
+		case "limit":
+			q.Limit, err = strconv.Atoi(value)
+		case "cursor":
+			q.Cursor = value
+		default:
+			err = fmt.Errorf("unknown key")
+		}
+		if err != nil {
+			return q, fmt.Errorf("invalid query argument [%s]: %v", arg, err)
+		}
+	}
+	return q, nil
+}
+
+func printSubscription(sub models.Subscriber) {
+	activity := "none"
+	if sub.LastActivityBlock > 0 {
+		activity = fmt.Sprintf("block %d", sub.LastActivityBlock)
+		if !sub.LastActivity.IsZero() {
+			activity += " at " + sub.LastActivity.Format(time.RFC3339)
+		}
+	}
+	fmt.Printf("  %s since block %d (%s): %d transactions, %d transfers, last activity %s\n",
+		sub.Address, sub.CreatedAtBlock, sub.CreatedAt.Format(time.RFC3339), sub.TxCount, sub.TransferCount, activity)
+}
+
+func printTransaction(tx models.Transaction) {
+	fee := "unknown"
+	if f := tx.Fee(); f != nil {
+		fee = f.String()
+	}
+	to := tx.To.Hex()
+	if tx.Kind == models.TxKindDeployment {
+		to = "new contract " + tx.ContractAddress.Hex()
+	}
+	delta := "unknown"
+	if tx.BalanceDelta != nil {
+		delta = tx.BalanceDelta.String()
+	}
+	fmt.Printf("  %s %s block=%v type=%s %s from=%s to=%s value=%v status=%s fee=%s delta=%s %s\n",
+		tx.Timestamp.Format(time.RFC3339), tx.Hash, tx.BlockNumber, tx.Type, tx.Direction, tx.From, to, tx.Value, tx.Status, fee, delta, tx.Confirmation)
+}
+
+func printEndpoints(endpoints []ethclient.EndpointStatus) {
+	fmt.Println("Endpoints:")
+	for _, e := range endpoints {
+		status := "up"
+		if !e.Healthy {
+			status = "down"
+		}
+		fmt.Printf("  %s [%s] head=%d lag=%d requests=%d failures=%d", e.URL, status, e.Head, e.Lag, e.Requests, e.Failures)
+		if e.LastError != nil {
+			fmt.Printf(" last error: %v", e.LastError)
+		}
+		fmt.Println()
+	}
+}
+
+func printBackfills(backfills []scannersvc.BackfillStatus) {
+	if len(backfills) == 0 {
+		return
+	}
+	fmt.Println("Backfills:")
+	for _, b := range backfills {
+		state := "running"
+		switch {
+		case b.Err != nil:
+			state = "failed: " + b.Err.Error()
+		case b.Done:
+			state = "done"
+		}
+		total := b.To - b.From + 1
+		fmt.Printf("  %s blocks %d-%d: %d/%d scanned, %d transactions and %d token transfers found, %s\n",
+			b.Address, b.From, b.To, b.Current-b.From+1, total, b.Found, b.Tokens, state)
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/trust-assignment/pkg/crypto"
+)
+
+// AddressLength is the length of an address in bytes.
+const AddressLength = 20
+
+var (
+	// ErrInvalidAddress is returned by ParseAddress for input that is not a
+	// 0x-prefixed 20-byte hex string.
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrAddressChecksum is returned by ParseAddress for mixed-case input
+	// whose EIP-55 checksum does not match. It wraps ErrInvalidAddress.
+	ErrAddressChecksum = fmt.Errorf("%w: bad EIP-55 checksum", ErrInvalidAddress)
+)
+
+// Address is a 20-byte account or contract address. Addresses compare
+// equal regardless of the casing they were parsed from, and print in their
+// EIP-55 checksummed form. The zero Address is also used for the missing
+// recipient of a contract deployment.
+type Address [AddressLength]byte
+
+// ParseAddress parses a 0x-prefixed hex address. All-lowercase and
+// all-uppercase input is accepted as is; mixed-case input must carry a
+// valid EIP-55 checksum.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok || len(digits) != 2*AddressLength {
+		return a, fmt.Errorf("[%s]: %w", s, ErrInvalidAddress)
+	}
+	if _, err := hex.Decode(a[:], []byte(digits)); err != nil {
+		return Address{}, fmt.Errorf("[%s]: %w", s, ErrInvalidAddress)
+	}
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && a.Hex() != s {
+		return Address{}, fmt.Errorf("[%s]: %w", s, ErrAddressChecksum)
+	}
+	return a, nil
+}
+
+// HexToAddress converts an address reported by the node, ignoring its
+// casing. Empty or malformed input yields the zero Address.
+func HexToAddress(s string) Address {
+	var a Address
+	digits := strings.TrimPrefix(strings.ToLower(s), "0x")
+	if len(digits) != 2*AddressLength {
+		return a
+	}
+	if _, err := hex.Decode(a[:], []byte(digits)); err != nil {
+		return Address{}
+	}
+	return a
+}
+
+// IsZero reports whether a is the zero address.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// Hex returns the EIP-55 checksummed form of a: each hex letter is upper
+// case if the matching nibble of the Keccak-256 hash of the lowercase hex
+// is 8 or more.
+func (a Address) Hex() string {
+	buf := []byte(hex.EncodeToString(a[:]))
+	hash := crypto.Keccak256(buf)
+	for i, c := range buf {
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if c >= 'a' && nibble&0xf >= 8 {
+			buf[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(buf)
+}
+
+// String returns the checksummed form of a.
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// MarshalText encodes a in its checksummed form, so JSON carries it as a
+// string.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText parses text with ParseAddress.
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
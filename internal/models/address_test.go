@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddressChecksum(t *testing.T) {
+	// Test vectors from EIP-55.
+	for _, checksummed := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		lower, err := ParseAddress(strings.ToLower(checksummed))
+		if err != nil {
+			t.Fatalf("ParseAddress(%s) failed: %v", strings.ToLower(checksummed), err)
+		}
+		if lower.Hex() != checksummed {
+			t.Errorf("Hex() = %s, expected %s", lower.Hex(), checksummed)
+		}
+		parsed, err := ParseAddress(checksummed)
+		if err != nil || parsed != lower {
+			t.Errorf("ParseAddress(%s) = %s, %v", checksummed, parsed, err)
+		}
+	}
+}
+
+func TestParseAddressErrors(t *testing.T) {
+	for input, expected := range map[string]error{
+		"":   ErrInvalidAddress,
+		"0x": ErrInvalidAddress,
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed":     ErrInvalidAddress,
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beae":    ErrInvalidAddress,
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg":   ErrInvalidAddress,
+		"0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed":   ErrAddressChecksum,
+		"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED":   nil,
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00": ErrInvalidAddress,
+	} {
+		_, err := ParseAddress(input)
+		if !errors.Is(err, expected) || (expected == nil && err != nil) {
+			t.Errorf("ParseAddress(%q) = %v, expected %v", input, err, expected)
+		}
+	}
+	if _, err := ParseAddress("0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("checksum error %v does not match ErrInvalidAddress", err)
+	}
+}
+
+func TestAddressJSON(t *testing.T) {
+	var sub Subscriber
+	if err := json.Unmarshal([]byte(`{"address":"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}`), &sub); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	data, err := json.Marshal(sub.Address)
+	if err != nil || string(data) != `"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+	if err := json.Unmarshal([]byte(`{"address":"0xbad"}`), &sub); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("Unmarshal of a bad address = %v", err)
+	}
+}
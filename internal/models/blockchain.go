@@ -1,6 +1,188 @@
 package models
 
-import "math/big"
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ConfirmationStatus tracks how settled the block containing a transaction
+// is. Statuses only ever move forward as the chain advances.
+type ConfirmationStatus int
+
+const (
+	// Unconfirmed transactions are in a block with fewer confirmations than
+	// the scanner's configured depth.
+	Unconfirmed ConfirmationStatus = iota
+	// Confirmed transactions have at least the configured confirmations.
+	Confirmed
+	// Safe transactions are at or below the node's safe block.
+	Safe
+	// Finalized transactions are at or below the node's finalized block.
+	Finalized
+)
+
+func (c ConfirmationStatus) String() string {
+	switch c {
+	case Unconfirmed:
+		return "unconfirmed"
+	case Confirmed:
+		return "confirmed"
+	case Safe:
+		return "safe"
+	case Finalized:
+		return "finalized"
+	}
+	return "unknown"
+}
+
+func (c ConfirmationStatus) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// TxStatus is the execution outcome reported by a transaction receipt.
+type TxStatus int
+
+const (
+	// TxStatusUnknown means the receipt has not been fetched.
+	TxStatusUnknown TxStatus = iota
+	// TxStatusFailed means the transaction reverted.
+	TxStatusFailed
+	// TxStatusSuccess means the transaction executed successfully.
+	TxStatusSuccess
+)
+
+func (s TxStatus) String() string {
+	switch s {
+	case TxStatusFailed:
+		return "failed"
+	case TxStatusSuccess:
+		return "success"
+	}
+	return "unknown"
+}
+
+func (s TxStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// TxType is the EIP-2718 transaction type.
+type TxType int
+
+const (
+	LegacyTxType     TxType = 0 // pre-EIP-2718 transaction
+	AccessListTxType TxType = 1 // EIP-2930
+	DynamicFeeTxType TxType = 2 // EIP-1559
+	BlobTxType       TxType = 3 // EIP-4844
+	SetCodeTxType    TxType = 4 // EIP-7702
+)
+
+func (t TxType) String() string {
+	switch t {
+	case LegacyTxType:
+		return "legacy"
+	case AccessListTxType:
+		return "access-list"
+	case DynamicFeeTxType:
+		return "dynamic-fee"
+	case BlobTxType:
+		return "blob"
+	case SetCodeTxType:
+		return "set-code"
+	}
+	return fmt.Sprintf("type-%d", int(t))
+}
+
+func (t TxType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// TxKind distinguishes contract deployments from other transactions.
+type TxKind int
+
+const (
+	// TxKindCall is a value transfer or contract call.
+	TxKindCall TxKind = iota
+	// TxKindDeployment creates a contract; it has no recipient.
+	TxKindDeployment
+)
+
+func (k TxKind) String() string {
+	switch k {
+	case TxKindCall:
+		return "call"
+	case TxKindDeployment:
+		return "deployment"
+	}
+	return "unknown"
+}
+
+func (k TxKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Direction is which way a transaction moved relative to a subscribed
+// address.
+type Direction int
+
+const (
+	// DirectionAny matches every direction in a TxQuery. On a record it
+	// means the address is neither the sender nor the recipient.
+	DirectionAny Direction = iota
+	// DirectionIn is a transaction sent to the address by someone else.
+	DirectionIn
+	// DirectionOut is a transaction sent by the address to someone else,
+	// including deployments.
+	DirectionOut
+	// DirectionSelf is a transaction the address sent to itself.
+	DirectionSelf
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionAny:
+		return "any"
+	case DirectionIn:
+		return "in"
+	case DirectionOut:
+		return "out"
+	case DirectionSelf:
+		return "self"
+	}
+	return "unknown"
+}
+
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// DirectionOf returns the direction of a transfer from from to to, as seen
+// by address.
+func DirectionOf(address, from, to Address) Direction {
+	switch {
+	case from == address && to == address:
+		return DirectionSelf
+	case from == address:
+		return DirectionOut
+	case to == address:
+		return DirectionIn
+	}
+	return DirectionAny
+}
+
+// perspective returns the direction of a transfer from from to to as seen
+// by address, and the address on the other side.
+func perspective(address, from, to Address) (Direction, Address) {
+	switch d := DirectionOf(address, from, to); d {
+	case DirectionIn:
+		return d, from
+	case DirectionOut:
+		return d, to
+	case DirectionSelf:
+		return d, address
+	}
+	return DirectionAny, Address{}
+}
 
 type RequestBody struct {
 	Jsonrpc string      `json:"jsonrpc"`
@@ -9,47 +191,328 @@ type RequestBody struct {
 	Params  interface{} `json:"params"`
 }
 
+// Checkpoint is the scanner's cursor: the last block it finished scanning.
+type Checkpoint struct {
+	BlockNumber int    `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+}
+
+// Block is a block's header fields together with its transactions.
 type Block struct {
-	Number       string        `json:"number"`
-	Hash         string        `json:"hash"`
-	Transactions []Transaction `json:"transactions"`
+	Number        *big.Int      `json:"number"`
+	Hash          string        `json:"hash"`
+	ParentHash    string        `json:"parentHash"`
+	Timestamp     time.Time     `json:"timestamp"`
+	FeeRecipient  Address       `json:"feeRecipient"` // the miner before the merge
+	GasUsed       *big.Int      `json:"gasUsed"`
+	GasLimit      *big.Int      `json:"gasLimit"`
+	BaseFeePerGas *big.Int      `json:"baseFeePerGas,omitempty"` // nil before the London fork
+	BlobGasUsed   *big.Int      `json:"blobGasUsed,omitempty"`   // nil before the Cancun fork
+	ExcessBlobGas *big.Int      `json:"excessBlobGas,omitempty"` // nil before the Cancun fork
+	Withdrawals   []Withdrawal  `json:"withdrawals,omitempty"`
+	Transactions  []Transaction `json:"transactions"`
 }
 
+// Withdrawal is a beacon chain withdrawal credited in a block.
+type Withdrawal struct {
+	Index          uint64   `json:"index"`
+	ValidatorIndex uint64   `json:"validatorIndex"`
+	Address        Address  `json:"address"`
+	Amount         *big.Int `json:"amount"` // in gwei, as reported by the node
+}
+
+// AccessListEntry is an EIP-2930 access list entry: an account and the
+// storage slots of it the transaction pre-declares.
 type AccessListEntry struct {
-	Address     string   `json:"address"`
+	Address     Address  `json:"address"`
 	StorageKeys []string `json:"storageKeys"`
 }
 
-// these details we get from the blockchain in BlockByNumber function
-type RawTransaction struct {
-	ChainID          string            `json:"chainId"`
-	BlockNumber      string            `json:"blockNumber"`
-	BlockHash        string            `json:"-"`
-	Hash             string            `json:"hash"`
-	Nonce            string            `json:"nonce"`
-	From             string            `json:"from"`
-	To               string            `json:"to"`
-	Value            string            `json:"value"`
-	Gas              string            `json:"gas"`
-	GasPrice         string            `json:"gasPrice"`
-	Input            string            `json:"input"`
-	Type             string            `json:"-"`
-	R                string            `json:"-"`
-	S                string            `json:"-"`
-	V                string            `json:"-"`
-	TransactionIndex string            `json:"-"`
-	AccessList       []AccessListEntry `json:"-"`
+// Authorization is an EIP-7702 authorization to delegate the signing
+// account's code to Address.
+type Authorization struct {
+	ChainID *big.Int `json:"chainId"`
+	Address Address  `json:"address"`
+	Nonce   uint64   `json:"nonce"`
+	YParity uint8    `json:"yParity"`
+	R       *big.Int `json:"r"`
+	S       *big.Int `json:"s"`
 }
 
 type Transaction struct {
-	ChainID     *big.Int `json:"chainId"`
+	ChainID     *big.Int  `json:"chainId"`
+	BlockNumber *big.Int  `json:"blockNumber"`
+	BlockHash   string    `json:"blockHash"`
+	Timestamp   time.Time `json:"timestamp"` // time the block was produced
+	Hash        string    `json:"hash"`
+	Nonce       *big.Int  `json:"nonce"`
+	From        Address   `json:"from"`
+	To          Address   `json:"to"` // zero for deployments
+	Value       *big.Int  `json:"value"`
+	Gas         *big.Int  `json:"gas"`
+	GasPrice    *big.Int  `json:"gasPrice"`
+	Input       string    `json:"input"`
+	Kind        TxKind    `json:"kind"`
+	Type        TxType    `json:"type"`
+
+	// Typed transaction fields, set only for the types that define them.
+	AccessList           []AccessListEntry `json:"accessList,omitempty"`
+	MaxFeePerGas         *big.Int          `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *big.Int          `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerBlobGas     *big.Int          `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes  []string          `json:"blobVersionedHashes,omitempty"`
+	AuthorizationList    []Authorization   `json:"authorizationList,omitempty"`
+
+	Confirmation ConfirmationStatus `json:"confirmation"`
+
+	// Receipt details, filled in once the receipt has been fetched.
+	Status            TxStatus `json:"status"`
+	GasUsed           *big.Int `json:"gasUsed,omitempty"`
+	CumulativeGasUsed *big.Int `json:"cumulativeGasUsed,omitempty"`
+	// EffectiveGasPrice is known once the block's base fee is; the receipt
+	// confirms it.
+	EffectiveGasPrice *big.Int `json:"effectiveGasPrice,omitempty"`
+	// ContractAddress is the contract created by a deployment, taken from
+	// the receipt or derived from the sender and nonce.
+	ContractAddress Address `json:"contractAddress"`
+	Logs            []Log   `json:"logs,omitempty"`
+
+	// The subscriber's view of the transaction, set when it is stored for
+	// a subscriber. Counterparty is the new contract for deployments.
+	Direction    Direction `json:"direction"`
+	Counterparty Address   `json:"counterparty"`
+	// BalanceDelta is the change in the subscriber's ETH balance in wei:
+	// the value received, or minus the value and the fee for an outbound
+	// transaction. A reverted transaction only costs its sender the fee.
+	// It is nil until the receipt has been fetched.
+	BalanceDelta *big.Int `json:"balanceDelta,omitempty"`
+}
+
+// ForSubscriber returns a copy of t as seen by address, with Direction,
+// Counterparty and BalanceDelta set.
+func (t Transaction) ForSubscriber(address Address) Transaction {
+	t.Direction, t.Counterparty = perspective(address, t.From, t.To)
+	if t.Direction == DirectionOut && t.Kind == TxKindDeployment {
+		t.Counterparty = t.ContractAddress
+	}
+	t.BalanceDelta = t.balanceDelta()
+	return t
+}
+
+func (t Transaction) balanceDelta() *big.Int {
+	if t.Status == TxStatusUnknown {
+		return nil
+	}
+	delta := new(big.Int)
+	if t.Status == TxStatusSuccess && t.Value != nil {
+		switch t.Direction {
+		case DirectionIn:
+			delta.Set(t.Value)
+		case DirectionOut:
+			delta.Neg(t.Value)
+		}
+	}
+	if t.Direction == DirectionOut || t.Direction == DirectionSelf {
+		fee := t.Fee()
+		if fee == nil {
+			return nil
+		}
+		delta.Sub(delta, fee)
+	}
+	return delta
+}
+
+// Fee returns the amount actually paid for the transaction, gasUsed times
+// effectiveGasPrice, or nil if the receipt has not been fetched.
+func (t Transaction) Fee() *big.Int {
+	if t.GasUsed == nil || t.EffectiveGasPrice == nil {
+		return nil
+	}
+	return new(big.Int).Mul(t.GasUsed, t.EffectiveGasPrice)
+}
+
+// Log is an event emitted while executing a transaction.
+type Log struct {
+	Address  Address  `json:"address"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+	LogIndex uint64   `json:"logIndex"`
+}
+
+// TokenTransfer is an ERC-20 Transfer event involving a subscribed address.
+type TokenTransfer struct {
+	Token       Address  `json:"token"` // address of the token contract
+	From        Address  `json:"from"`
+	To          Address  `json:"to"`
+	Amount      *big.Int `json:"amount"`
+	TxHash      string   `json:"txHash"`
+	BlockNumber *big.Int `json:"blockNumber"`
+	BlockHash   string   `json:"blockHash"`
+	LogIndex    uint64   `json:"logIndex"`
+
+	Confirmation ConfirmationStatus `json:"confirmation"`
+
+	// The subscriber's view of the transfer, set when it is stored.
+	Direction    Direction `json:"direction"`
+	Counterparty Address   `json:"counterparty"`
+}
+
+// ForSubscriber returns a copy of t as seen by address, with Direction and
+// Counterparty set.
+func (t TokenTransfer) ForSubscriber(address Address) TokenTransfer {
+	t.Direction, t.Counterparty = perspective(address, t.From, t.To)
+	return t
+}
+
+// TokenStandard identifies the token interface an NFT transfer follows.
+type TokenStandard int
+
+const (
+	ERC721 TokenStandard = iota
+	ERC1155
+)
+
+func (s TokenStandard) String() string {
+	switch s {
+	case ERC721:
+		return "erc721"
+	case ERC1155:
+		return "erc1155"
+	}
+	return "unknown"
+}
+
+func (s TokenStandard) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// NFTTransfer is an ERC-721 Transfer or ERC-1155 TransferSingle/TransferBatch
+// event involving a subscribed address. A TransferBatch event is stored as
+// one NFTTransfer per token id, told apart by BatchIndex.
+type NFTTransfer struct {
+	Standard    TokenStandard `json:"standard"`
+	Contract    Address       `json:"contract"`
+	Operator    Address       `json:"operator"` // ERC-1155 only
+	From        Address       `json:"from"`
+	To          Address       `json:"to"`
+	TokenID     *big.Int      `json:"tokenId"`
+	Amount      *big.Int      `json:"amount"` // always 1 for ERC-721
+	TxHash      string        `json:"txHash"`
+	BlockNumber *big.Int      `json:"blockNumber"`
+	BlockHash   string        `json:"blockHash"`
+	LogIndex    uint64        `json:"logIndex"`
+	BatchIndex  int           `json:"batchIndex"`
+
+	Confirmation ConfirmationStatus `json:"confirmation"`
+
+	// The subscriber's view of the transfer, set when it is stored.
+	Direction    Direction `json:"direction"`
+	Counterparty Address   `json:"counterparty"`
+}
+
+// ForSubscriber returns a copy of n as seen by address, with Direction and
+// Counterparty set.
+func (n NFTTransfer) ForSubscriber(address Address) NFTTransfer {
+	n.Direction, n.Counterparty = perspective(address, n.From, n.To)
+	return n
+}
+
+// InternalTransfer is ETH moved, or a contract created, by a contract while
+// executing a transaction, as found by tracing the block. For a creation To
+// is the new contract and Value may be zero.
+type InternalTransfer struct {
+	TxHash      string   `json:"txHash"`
 	BlockNumber *big.Int `json:"blockNumber"`
-	Hash        string   `json:"hash"`
-	Nonce       *big.Int `json:"nonce"`
-	From        string   `json:"from"`
-	To          string   `json:"to"`
+	BlockHash   string   `json:"blockHash"`
+	Type        string   `json:"type"` // call, create or selfdestruct
+	From        Address  `json:"from"`
+	To          Address  `json:"to"`
 	Value       *big.Int `json:"value"`
-	Gas         *big.Int `json:"gas"`
-	GasPrice    *big.Int `json:"gasPrice"`
-	Input       string   `json:"input"`
+	// TraceAddress locates the call in the transaction's call tree, e.g.
+	// "0-2".
+	TraceAddress string `json:"traceAddress"`
+
+	Confirmation ConfirmationStatus `json:"confirmation"`
+
+	// The subscriber's view of the transfer, set when it is stored.
+	Direction    Direction `json:"direction"`
+	Counterparty Address   `json:"counterparty"`
+}
+
+// ForSubscriber returns a copy of t as seen by address, with Direction and
+// Counterparty set.
+func (t InternalTransfer) ForSubscriber(address Address) InternalTransfer {
+	t.Direction, t.Counterparty = perspective(address, t.From, t.To)
+	return t
+}
+
+// WithdrawalTransfer is a beacon chain withdrawal credited to a subscribed
+// address. Unlike Withdrawal, the amount is converted to wei.
+type WithdrawalTransfer struct {
+	Index          uint64    `json:"index"`
+	ValidatorIndex uint64    `json:"validatorIndex"`
+	Address        Address   `json:"address"`
+	Amount         *big.Int  `json:"amount"` // in wei
+	BlockNumber    *big.Int  `json:"blockNumber"`
+	BlockHash      string    `json:"blockHash"`
+	Timestamp      time.Time `json:"timestamp"`
+
+	Confirmation ConfirmationStatus `json:"confirmation"`
+}
+
+// PendingState tracks a mempool transaction until it is mined or abandoned.
+type PendingState int
+
+const (
+	// Pending transactions are waiting in the mempool.
+	Pending PendingState = iota
+	// Mined transactions were included in a block; the scanner stores
+	// them with the other transactions.
+	Mined
+	// Replaced transactions lost to another transaction from the same
+	// sender with the same nonce.
+	Replaced
+	// Dropped transactions left the mempool without being mined.
+	Dropped
+)
+
+func (s PendingState) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Mined:
+		return "mined"
+	case Replaced:
+		return "replaced"
+	case Dropped:
+		return "dropped"
+	}
+	return "unknown"
+}
+
+func (s PendingState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// PendingTransaction is a mempool transaction sent from or to a subscribed
+// address. Once settled, BlockNumber is the block that mined or replaced
+// it.
+type PendingTransaction struct {
+	Transaction
+	State      PendingState `json:"state"`
+	FirstSeen  time.Time    `json:"firstSeen"`
+	ReplacedBy string       `json:"replacedBy,omitempty"` // hash of the mined transaction with the same nonce
+}
+
+// Subscriber summarises a watched address.
+type Subscriber struct {
+	Address        Address   `json:"address"`
+	CreatedAt      time.Time `json:"createdAt"`
+	CreatedAtBlock int       `json:"createdAtBlock"` // last scanned block when subscribed, 0 if unknown
+	TxCount        int       `json:"txCount"`
+	// TransferCount counts token, NFT and internal transfers and
+	// withdrawals.
+	TransferCount     int       `json:"transferCount"`
+	LastActivityBlock int       `json:"lastActivityBlock"` // 0 if there was no activity
+	LastActivity      time.Time `json:"lastActivity"`      // zero if unknown
 }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTransactionForSubscriber(t *testing.T) {
+	me := HexToAddress("0x00000000000000000000000000000000000a11ce")
+	other := HexToAddress("0x0000000000000000000000000000000000000b0b")
+	contract := HexToAddress("0x000000000000000000000000000000000000c0de")
+	tx := func(from, to Address, status TxStatus) Transaction {
+		return Transaction{From: from, To: to, Value: big.NewInt(1000), Status: status, GasUsed: big.NewInt(21), EffectiveGasPrice: big.NewInt(2)}
+	}
+	deployment := tx(me, Address{}, TxStatusSuccess)
+	deployment.Kind = TxKindDeployment
+	deployment.ContractAddress = contract
+
+	tests := []struct {
+		name         string
+		tx           Transaction
+		direction    Direction
+		counterparty Address
+		delta        string
+	}{
+		{"inbound", tx(other, me, TxStatusSuccess), DirectionIn, other, "1000"},
+		{"inbound reverted", tx(other, me, TxStatusFailed), DirectionIn, other, "0"},
+		{"outbound", tx(me, other, TxStatusSuccess), DirectionOut, other, "-1042"},
+		{"outbound reverted", tx(me, other, TxStatusFailed), DirectionOut, other, "-42"},
+		{"self", tx(me, me, TxStatusSuccess), DirectionSelf, me, "-42"},
+		{"deployment", deployment, DirectionOut, contract, "-1042"},
+		{"no receipt", tx(other, me, TxStatusUnknown), DirectionIn, other, "<nil>"},
+	}
+	for _, test := range tests {
+		got := test.tx.ForSubscriber(me)
+		if got.Direction != test.direction || got.Counterparty != test.counterparty || got.BalanceDelta.String() != test.delta {
+			t.Errorf("%s: got %v %v %v, expected %v %v %s",
+				test.name, got.Direction, got.Counterparty, got.BalanceDelta, test.direction, test.counterparty, test.delta)
+		}
+	}
+}
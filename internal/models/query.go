@@ -0,0 +1,60 @@
+package models
+
+import (
+	"math/big"
+	"time"
+)
+
+// SortOrder is the block order a query returns transactions in.
+type SortOrder int
+
+const (
+	// Ascending returns the oldest transactions first.
+	Ascending SortOrder = iota
+	// Descending returns the newest transactions first.
+	Descending
+)
+
+func (o SortOrder) String() string {
+	if o == Descending {
+		return "desc"
+	}
+	return "asc"
+}
+
+// DefaultQueryLimit is the page size of a TxQuery without a Limit.
+const DefaultQueryLimit = 100
+
+// TxQuery selects a page of a subscriber's transactions. The zero value
+// matches every transaction and returns the first DefaultQueryLimit, oldest
+// first.
+type TxQuery struct {
+	Direction Direction
+	// FromBlock and ToBlock bound the block range, inclusive. 0 leaves
+	// that end open.
+	FromBlock int
+	ToBlock   int
+	// Since and Until bound the block time to [Since, Until). A zero time
+	// leaves that end open.
+	Since time.Time
+	Until time.Time
+	// MinValue and MaxValue bound the value in wei, inclusive. nil leaves
+	// that end open.
+	MinValue *big.Int
+	MaxValue *big.Int
+	// Status, if set, only matches transactions with that receipt status.
+	Status *TxStatus
+	Order  SortOrder
+	// Limit is the maximum page size; 0 means DefaultQueryLimit.
+	Limit int
+	// Cursor continues a previous query from its TxPage.NextCursor. The
+	// other fields must be unchanged.
+	Cursor string
+}
+
+// TxPage is one page of a TxQuery's results.
+type TxPage struct {
+	Transactions []Transaction `json:"transactions"`
+	// NextCursor fetches the next page; it is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
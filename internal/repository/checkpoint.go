@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+// ErrNoCheckpoint is returned by LoadCheckpoint when nothing was saved yet.
+var ErrNoCheckpoint = errors.New("[DB-error] No checkpoint saved")
+
+// FileCheckpoint persists the scanner checkpoint as a JSON file so scanning
+// can resume after a restart.
+type FileCheckpoint struct {
+	path string
+	mu   *sync.Mutex
+}
+
+// NewFileCheckpoint returns a checkpoint store backed by the file at path.
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{
+		path: path,
+		mu:   &sync.Mutex{},
+	}
+}
+
+// SaveCheckpoint atomically replaces the stored checkpoint.
+func (f *FileCheckpoint) SaveCheckpoint(ctx context.Context, cp models.Checkpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("[DB-error] Error encoding checkpoint: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("[DB-error] Error writing checkpoint: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("[DB-error] Error writing checkpoint: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("[DB-error] Error writing checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("[DB-error] Error writing checkpoint: %v", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint returns the stored checkpoint, or ErrNoCheckpoint if the
+// file does not exist.
+func (f *FileCheckpoint) LoadCheckpoint(ctx context.Context) (models.Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var cp models.Checkpoint
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, ErrNoCheckpoint
+	}
+	if err != nil {
+		return cp, fmt.Errorf("[DB-error] Error reading checkpoint: %v", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("[DB-error] Error decoding checkpoint: %v", err)
+	}
+	return cp, nil
+}
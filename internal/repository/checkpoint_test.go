@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+func TestFileCheckpoint(t *testing.T) {
+	store := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	if _, err := store.LoadCheckpoint(context.Background()); !errors.Is(err, ErrNoCheckpoint) {
+		t.Fatalf("expected ErrNoCheckpoint before saving, got %v", err)
+	}
+
+	cp := models.Checkpoint{BlockNumber: 19000000, BlockHash: "0xabc"}
+	if err := store.SaveCheckpoint(context.Background(), cp); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+	cp.BlockNumber++
+	if err := store.SaveCheckpoint(context.Background(), cp); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	loaded, err := store.LoadCheckpoint(context.Background())
+	if err != nil || loaded != cp {
+		t.Errorf("LoadCheckpoint = %+v, %v; expected %+v", loaded, err, cp)
+	}
+}
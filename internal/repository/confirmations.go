@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+// unfinalizedBlock lists the subscribers with records in a block that is not
+// yet finalized, and the status PromoteTxns last raised the block to.
+type unfinalizedBlock struct {
+	status    models.ConfirmationStatus
+	addresses map[models.Address]struct{}
+}
+
+// promotedBounds are the highest bounds PromoteTxns was called with.
+type promotedBounds struct {
+	confirmed, safe, finalized int
+}
+
+// status returns the confirmation status the bounds give block.
+func (b promotedBounds) status(block int64) models.ConfirmationStatus {
+	switch {
+	case b.finalized > 0 && block <= int64(b.finalized):
+		return models.Finalized
+	case b.safe > 0 && block <= int64(b.safe):
+		return models.Safe
+	case b.confirmed > 0 && block <= int64(b.confirmed):
+		return models.Confirmed
+	}
+	return models.Unconfirmed
+}
+
+// track prepares a record of address in blockNumber for storing: it raises
+// status to what the bounds already crossed give the block, and indexes the
+// block until it is finalized. The caller must hold the write lock.
+func (m *MemoryDb) track(address models.Address, blockNumber *big.Int, status *models.ConfirmationStatus) {
+	if blockNumber == nil {
+		return
+	}
+	block := blockNumber.Int64()
+	if level := m.promoted.status(block); *status < level {
+		*status = level
+	}
+	if *status == models.Finalized {
+		return
+	}
+	entry, ok := m.unfinalized[block]
+	if !ok {
+		entry = &unfinalizedBlock{status: m.promoted.status(block), addresses: make(map[models.Address]struct{})}
+		m.unfinalized[block] = entry
+	}
+	entry.addresses[address] = struct{}{}
+}
+
+// PromoteTxns upgrades the confirmation status of stored transactions,
+// token, NFT and internal transfers and withdrawals as the chain advances:
+// records at or below finalizedUpTo become Finalized, at or below safeUpTo
+// Safe and at or below confirmedUpTo Confirmed. A bound of 0 or less is
+// ignored and statuses are never downgraded. Only the blocks not yet
+// finalized are visited, and of those only the ones whose status changes.
+func (m *MemoryDb) PromoteTxns(ctx context.Context, confirmedUpTo, safeUpTo, finalizedUpTo int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.promoted = promotedBounds{
+		confirmed: max(m.promoted.confirmed, confirmedUpTo),
+		safe:      max(m.promoted.safe, safeUpTo),
+		finalized: max(m.promoted.finalized, finalizedUpTo),
+	}
+	for block, entry := range m.unfinalized {
+		status := m.promoted.status(block)
+		if status <= entry.status {
+			continue
+		}
+		for address := range entry.addresses {
+			promoteBlock(m.Db[address], block, status, func(tx *models.Transaction) (*big.Int, *models.ConfirmationStatus) {
+				return tx.BlockNumber, &tx.Confirmation
+			})
+			promoteBlock(m.Transfers[address], block, status, func(t *models.TokenTransfer) (*big.Int, *models.ConfirmationStatus) {
+				return t.BlockNumber, &t.Confirmation
+			})
+			promoteBlock(m.NFTs[address], block, status, func(n *models.NFTTransfer) (*big.Int, *models.ConfirmationStatus) {
+				return n.BlockNumber, &n.Confirmation
+			})
+			promoteBlock(m.Internal[address], block, status, func(t *models.InternalTransfer) (*big.Int, *models.ConfirmationStatus) {
+				return t.BlockNumber, &t.Confirmation
+			})
+			promoteBlock(m.Withdrawals[address], block, status, func(w *models.WithdrawalTransfer) (*big.Int, *models.ConfirmationStatus) {
+				return w.BlockNumber, &w.Confirmation
+			})
+		}
+		if status == models.Finalized {
+			delete(m.unfinalized, block)
+		} else {
+			entry.status = status
+		}
+	}
+	return nil
+}
+
+// promoteBlock raises the records of block to status. records must be in
+// block order, as every stored list is; fields returns a record's block
+// number and status.
+func promoteBlock[T any](records []T, block int64, status models.ConfirmationStatus, fields func(*T) (*big.Int, *models.ConfirmationStatus)) {
+	blockAt := func(i int) int64 {
+		number, _ := fields(&records[i])
+		return blockNumberOf(number)
+	}
+	for i := sort.Search(len(records), func(i int) bool { return blockAt(i) >= block }); i < len(records) && blockAt(i) == block; i++ {
+		if _, s := fields(&records[i]); *s < status {
+			*s = status
+		}
+	}
+}
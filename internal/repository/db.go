@@ -1,94 +1,721 @@
 package repository
 
 import (
+	"bytes"
 	"context"
-	"fmt"
-	"strings"
+	"math/big"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/trust-assignment/internal/models"
 )
 
 // MemoryDb represents an in-memory database.
 type MemoryDb struct {
-	Db map[string][]models.Transaction // Internal storage for transactions, indexed by address
-	mu *sync.RWMutex                   // Mutex for concurrent access to the database
+	Db          map[models.Address][]models.Transaction        // Internal storage for transactions, indexed by address
+	Transfers   map[models.Address][]models.TokenTransfer      // Token transfers, indexed by address
+	NFTs        map[models.Address][]models.NFTTransfer        // NFT transfers, indexed by address
+	Internal    map[models.Address][]models.InternalTransfer   // Internal ETH transfers, indexed by address
+	Withdrawals map[models.Address][]models.WithdrawalTransfer // Beacon chain withdrawals, indexed by address
+	Pending     map[models.Address][]models.PendingTransaction // Mempool transactions, indexed by address
+	subscribers map[models.Address]models.Subscriber           // When each address was subscribed
+	txIndexes   map[models.Address]*txIndex                    // Positions in Db by direction, for QueryTxns
+	unfinalized map[int64]*unfinalizedBlock                    // Blocks with records not yet finalized, for PromoteTxns
+	promoted    promotedBounds                                 // Bounds of the PromoteTxns calls so far
+	mu          *sync.RWMutex                                  // Mutex for concurrent access to the database
 }
 
 // NewDB creates and returns a new instance of MemoryDb.
 func NewDB() *MemoryDb {
 	return &MemoryDb{
-		Db: make(map[string][]models.Transaction),
-		mu: &sync.RWMutex{},
+		Db:          make(map[models.Address][]models.Transaction),
+		Transfers:   make(map[models.Address][]models.TokenTransfer),
+		NFTs:        make(map[models.Address][]models.NFTTransfer),
+		Internal:    make(map[models.Address][]models.InternalTransfer),
+		Withdrawals: make(map[models.Address][]models.WithdrawalTransfer),
+		Pending:     make(map[models.Address][]models.PendingTransaction),
+		subscribers: make(map[models.Address]models.Subscriber),
+		txIndexes:   make(map[models.Address]*txIndex),
+		unfinalized: make(map[int64]*unfinalizedBlock),
+		mu:          &sync.RWMutex{},
 	}
 }
 
 // AddSubscriber adds a new subscriber with the given address to the database.
-func (m *MemoryDb) AddSubscriber(ctx context.Context, address string) error {
+func (m *MemoryDb) AddSubscriber(ctx context.Context, address models.Address) error {
+	return m.AddSubscriberAt(ctx, address, 0)
+}
+
+// AddSubscriberAt adds a new subscriber and records block as the block the
+// subscription was created at.
+func (m *MemoryDb) AddSubscriberAt(ctx context.Context, address models.Address, block int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	address = strings.ToLower(address)
 	if _, ok := m.Db[address]; ok {
-		return fmt.Errorf("[DB-error] Address already exists")
+		return ErrAlreadySubscribed
 	}
+	m.subscribers[address] = models.Subscriber{Address: address, CreatedAt: time.Now(), CreatedAtBlock: block}
 	m.Db[address] = []models.Transaction{}
+	m.txIndexes[address] = &txIndex{}
+	m.Transfers[address] = []models.TokenTransfer{}
+	m.NFTs[address] = []models.NFTTransfer{}
+	m.Internal[address] = []models.InternalTransfer{}
+	m.Withdrawals[address] = []models.WithdrawalTransfer{}
+	m.Pending[address] = []models.PendingTransaction{}
 	return nil
 }
 
-// CheckTxns checks if transactions exist for the specified address in the database.
-func (m *MemoryDb) CheckTxns(ctx context.Context, address string) (bool, error) {
+// CountSubscribers returns how many addresses are subscribed.
+func (m *MemoryDb) CountSubscribers(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.Db), nil
+}
+
+// ListSubscribers returns a summary of every subscriber, ordered by
+// address.
+func (m *MemoryDb) ListSubscribers(ctx context.Context) ([]models.Subscriber, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	address = strings.ToLower(address)
+	subscribers := make([]models.Subscriber, 0, len(m.Db))
+	for address := range m.Db {
+		subscribers = append(subscribers, m.summary(address))
+	}
+	sort.Slice(subscribers, func(i, j int) bool {
+		return bytes.Compare(subscribers[i].Address[:], subscribers[j].Address[:]) < 0
+	})
+	return subscribers, nil
+}
+
+// GetSubscriber returns a summary of the specified subscriber.
+func (m *MemoryDb) GetSubscriber(ctx context.Context, address models.Address) (models.Subscriber, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.Db[address]; !ok {
+		return models.Subscriber{}, ErrNotSubscribed
+	}
+	return m.summary(address), nil
+}
+
+// summary counts the records stored for address and finds its latest
+// activity. The caller must hold the lock.
+func (m *MemoryDb) summary(address models.Address) models.Subscriber {
+	sub := m.subscribers[address]
+	sub.Address = address
+	sub.TxCount = len(m.Db[address])
+	sub.TransferCount = len(m.Transfers[address]) + len(m.NFTs[address]) + len(m.Internal[address]) + len(m.Withdrawals[address])
+
+	seen := func(block *big.Int, at time.Time) {
+		if n := int(blockNumberOf(block)); n > sub.LastActivityBlock {
+			sub.LastActivityBlock = n
+		}
+		if at.After(sub.LastActivity) {
+			sub.LastActivity = at
+		}
+	}
+	for _, tx := range m.Db[address] {
+		seen(tx.BlockNumber, tx.Timestamp)
+	}
+	for _, t := range m.Transfers[address] {
+		seen(t.BlockNumber, time.Time{})
+	}
+	for _, n := range m.NFTs[address] {
+		seen(n.BlockNumber, time.Time{})
+	}
+	for _, t := range m.Internal[address] {
+		seen(t.BlockNumber, time.Time{})
+	}
+	for _, w := range m.Withdrawals[address] {
+		seen(w.BlockNumber, w.Timestamp)
+	}
+	return sub
+}
+
+// CheckTxns checks if transactions exist for the specified address in the database.
+func (m *MemoryDb) CheckTxns(ctx context.Context, address models.Address) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
 	if _, ok := m.Db[address]; ok {
 		return true, nil
 	}
-	return false, fmt.Errorf("[DB-error] Address does not exist")
+	return false, ErrNotSubscribed
 }
 
 // GetTxns retrieves transactions for the specified address from the database.
-func (m *MemoryDb) GetTxns(ctx context.Context, address string) ([]models.Transaction, error) {
+func (m *MemoryDb) GetTxns(ctx context.Context, address models.Address) ([]models.Transaction, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	address = strings.ToLower(address)
-
 	if txns, ok := m.Db[address]; ok {
 		result := make([]models.Transaction, len(txns))
 		copy(result, txns)
 		return result, nil
 	}
-	return nil, fmt.Errorf("[DB-error] Address not found")
+	return nil, ErrNotSubscribed
 }
 
 // SaveTxns saves new transactions for multiple addresses to the database.
-func (m *MemoryDb) SaveTxns(ctx context.Context, newTxs map[string][]models.Transaction) error {
+// Addresses that are no longer subscribed are skipped; the other Save
+// methods do the same.
+func (m *MemoryDb) SaveTxns(ctx context.Context, newTxs map[models.Address][]models.Transaction) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for address, txs := range newTxs {
-		address = strings.ToLower(address)
-
-		// Check if the address exists in the memory database
+		// Skip addresses that unsubscribed after the block was scanned
 		if _, ok := m.Db[address]; !ok {
-			return fmt.Errorf("[DB-error] Address does not exist")
+			continue
 		}
 
 		// Append the new transactions to the existing transactions for the address
-		m.Db[address] = append(m.Db[address], txs...)
+		ix := m.txIndexes[address]
+		for _, tx := range txs {
+			tx = tx.ForSubscriber(address)
+			m.track(address, tx.BlockNumber, &tx.Confirmation)
+			ix.add(len(m.Db[address]), tx)
+			m.Db[address] = append(m.Db[address], tx)
+		}
+	}
+
+	return nil
+}
+
+// MergeTxns inserts historical transactions for a single address, keeping
+// the stored list ordered by block number and skipping transactions that
+// are already stored.
+func (m *MemoryDb) MergeTxns(ctx context.Context, address models.Address, txs []models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.Db[address]
+	if !ok {
+		return ErrNotSubscribed
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, tx := range existing {
+		seen[tx.Hash] = true
+	}
+	merged := existing
+	for _, tx := range txs {
+		if !seen[tx.Hash] {
+			seen[tx.Hash] = true
+			tx = tx.ForSubscriber(address)
+			m.track(address, tx.BlockNumber, &tx.Confirmation)
+			merged = append(merged, tx)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return blockOf(merged[i]) < blockOf(merged[j])
+	})
+	m.Db[address] = merged
+	m.reindex(address)
+	return nil
+}
+
+func blockOf(tx models.Transaction) int64 {
+	return blockNumberOf(tx.BlockNumber)
+}
+
+// GetTransfers retrieves token transfers for the specified address.
+func (m *MemoryDb) GetTransfers(ctx context.Context, address models.Address) ([]models.TokenTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if transfers, ok := m.Transfers[address]; ok {
+		result := make([]models.TokenTransfer, len(transfers))
+		copy(result, transfers)
+		return result, nil
+	}
+	return nil, ErrNotSubscribed
+}
+
+// SaveTransfers saves new token transfers for multiple addresses.
+func (m *MemoryDb) SaveTransfers(ctx context.Context, newTransfers map[models.Address][]models.TokenTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for address, transfers := range newTransfers {
+		if _, ok := m.Transfers[address]; !ok {
+			continue
+		}
+		for _, t := range transfers {
+			t = t.ForSubscriber(address)
+			m.track(address, t.BlockNumber, &t.Confirmation)
+			m.Transfers[address] = append(m.Transfers[address], t)
+		}
+	}
+	return nil
+}
+
+// MergeTransfers inserts historical token transfers for a single address,
+// like MergeTxns. Transfers are identified by transaction hash and log index.
+func (m *MemoryDb) MergeTransfers(ctx context.Context, address models.Address, transfers []models.TokenTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.Transfers[address]
+	if !ok {
+		return ErrNotSubscribed
+	}
+
+	type key struct {
+		hash  string
+		index uint64
+	}
+	seen := make(map[key]bool, len(existing))
+	for _, t := range existing {
+		seen[key{t.TxHash, t.LogIndex}] = true
+	}
+	merged := existing
+	for _, t := range transfers {
+		if k := (key{t.TxHash, t.LogIndex}); !seen[k] {
+			seen[k] = true
+			t = t.ForSubscriber(address)
+			m.track(address, t.BlockNumber, &t.Confirmation)
+			merged = append(merged, t)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		bi, bj := blockNumberOf(merged[i].BlockNumber), blockNumberOf(merged[j].BlockNumber)
+		if bi != bj {
+			return bi < bj
+		}
+		return merged[i].LogIndex < merged[j].LogIndex
+	})
+	m.Transfers[address] = merged
+	return nil
+}
+
+// GetNFTTransfers retrieves NFT transfers for the specified address.
+func (m *MemoryDb) GetNFTTransfers(ctx context.Context, address models.Address) ([]models.NFTTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if nfts, ok := m.NFTs[address]; ok {
+		result := make([]models.NFTTransfer, len(nfts))
+		copy(result, nfts)
+		return result, nil
+	}
+	return nil, ErrNotSubscribed
+}
+
+// NFTsReceived returns the NFT transfers to address mined between fromBlock
+// and toBlock, inclusive.
+func (m *MemoryDb) NFTsReceived(ctx context.Context, address models.Address, fromBlock, toBlock int) ([]models.NFTTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nfts, ok := m.NFTs[address]
+	if !ok {
+		return nil, ErrNotSubscribed
+	}
+	var result []models.NFTTransfer
+	for _, n := range nfts {
+		block := blockNumberOf(n.BlockNumber)
+		if n.To == address && block >= int64(fromBlock) && block <= int64(toBlock) {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+// SaveNFTTransfers saves new NFT transfers for multiple addresses.
+func (m *MemoryDb) SaveNFTTransfers(ctx context.Context, newNFTs map[models.Address][]models.NFTTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for address, nfts := range newNFTs {
+		if _, ok := m.NFTs[address]; !ok {
+			continue
+		}
+		for _, n := range nfts {
+			n = n.ForSubscriber(address)
+			m.track(address, n.BlockNumber, &n.Confirmation)
+			m.NFTs[address] = append(m.NFTs[address], n)
+		}
+	}
+	return nil
+}
+
+// MergeNFTTransfers inserts historical NFT transfers for a single address,
+// like MergeTxns. Transfers are identified by transaction hash, log index
+// and batch index.
+func (m *MemoryDb) MergeNFTTransfers(ctx context.Context, address models.Address, nfts []models.NFTTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.NFTs[address]
+	if !ok {
+		return ErrNotSubscribed
+	}
+
+	type key struct {
+		hash  string
+		index uint64
+		batch int
+	}
+	seen := make(map[key]bool, len(existing))
+	for _, n := range existing {
+		seen[key{n.TxHash, n.LogIndex, n.BatchIndex}] = true
+	}
+	merged := existing
+	for _, n := range nfts {
+		if k := (key{n.TxHash, n.LogIndex, n.BatchIndex}); !seen[k] {
+			seen[k] = true
+			n = n.ForSubscriber(address)
+			m.track(address, n.BlockNumber, &n.Confirmation)
+			merged = append(merged, n)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		bi, bj := blockNumberOf(merged[i].BlockNumber), blockNumberOf(merged[j].BlockNumber)
+		if bi != bj {
+			return bi < bj
+		}
+		if merged[i].LogIndex != merged[j].LogIndex {
+			return merged[i].LogIndex < merged[j].LogIndex
+		}
+		return merged[i].BatchIndex < merged[j].BatchIndex
+	})
+	m.NFTs[address] = merged
+	return nil
+}
+
+// GetInternalTransfers retrieves internal ETH transfers for the specified
+// address.
+func (m *MemoryDb) GetInternalTransfers(ctx context.Context, address models.Address) ([]models.InternalTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if internal, ok := m.Internal[address]; ok {
+		result := make([]models.InternalTransfer, len(internal))
+		copy(result, internal)
+		return result, nil
+	}
+	return nil, ErrNotSubscribed
+}
+
+// SaveInternalTransfers saves new internal ETH transfers for multiple
+// addresses.
+func (m *MemoryDb) SaveInternalTransfers(ctx context.Context, newInternal map[models.Address][]models.InternalTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for address, internal := range newInternal {
+		if _, ok := m.Internal[address]; !ok {
+			continue
+		}
+		for _, t := range internal {
+			t = t.ForSubscriber(address)
+			m.track(address, t.BlockNumber, &t.Confirmation)
+			m.Internal[address] = append(m.Internal[address], t)
+		}
+	}
+	return nil
+}
+
+// MergeInternalTransfers inserts historical internal ETH transfers for a
+// single address, like MergeTxns. Transfers are identified by transaction
+// hash and trace address.
+func (m *MemoryDb) MergeInternalTransfers(ctx context.Context, address models.Address, internal []models.InternalTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.Internal[address]
+	if !ok {
+		return ErrNotSubscribed
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		seen[t.TxHash+":"+t.TraceAddress] = true
 	}
+	merged := existing
+	for _, t := range internal {
+		if k := t.TxHash + ":" + t.TraceAddress; !seen[k] {
+			seen[k] = true
+			t = t.ForSubscriber(address)
+			m.track(address, t.BlockNumber, &t.Confirmation)
+			merged = append(merged, t)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return blockNumberOf(merged[i].BlockNumber) < blockNumberOf(merged[j].BlockNumber)
+	})
+	m.Internal[address] = merged
+	return nil
+}
+
+// GetWithdrawals retrieves the beacon chain withdrawals credited to the
+// specified address.
+func (m *MemoryDb) GetWithdrawals(ctx context.Context, address models.Address) ([]models.WithdrawalTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if withdrawals, ok := m.Withdrawals[address]; ok {
+		result := make([]models.WithdrawalTransfer, len(withdrawals))
+		copy(result, withdrawals)
+		return result, nil
+	}
+	return nil, ErrNotSubscribed
+}
+
+// SaveWithdrawals saves new beacon chain withdrawals for multiple addresses.
+func (m *MemoryDb) SaveWithdrawals(ctx context.Context, newWithdrawals map[models.Address][]models.WithdrawalTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	for address, withdrawals := range newWithdrawals {
+		if _, ok := m.Withdrawals[address]; !ok {
+			continue
+		}
+		for _, w := range withdrawals {
+			m.track(address, w.BlockNumber, &w.Confirmation)
+			m.Withdrawals[address] = append(m.Withdrawals[address], w)
+		}
+	}
+	return nil
+}
+
+// MergeWithdrawals inserts historical withdrawals for a single address, like
+// MergeTxns. Withdrawals are identified by their global index.
+func (m *MemoryDb) MergeWithdrawals(ctx context.Context, address models.Address, withdrawals []models.WithdrawalTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.Withdrawals[address]
+	if !ok {
+		return ErrNotSubscribed
+	}
+
+	seen := make(map[uint64]bool, len(existing))
+	for _, w := range existing {
+		seen[w.Index] = true
+	}
+	merged := existing
+	for _, w := range withdrawals {
+		if !seen[w.Index] {
+			seen[w.Index] = true
+			m.track(address, w.BlockNumber, &w.Confirmation)
+			merged = append(merged, w)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Index < merged[j].Index
+	})
+	m.Withdrawals[address] = merged
 	return nil
 }
 
+// GetPending retrieves the mempool transactions seen for the specified
+// address, in every state.
+func (m *MemoryDb) GetPending(ctx context.Context, address models.Address) ([]models.PendingTransaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if pending, ok := m.Pending[address]; ok {
+		result := make([]models.PendingTransaction, len(pending))
+		copy(result, pending)
+		return result, nil
+	}
+	return nil, ErrNotSubscribed
+}
+
+// SavePending records a mempool transaction for address. It reports false
+// if the transaction was already recorded.
+func (m *MemoryDb) SavePending(ctx context.Context, address models.Address, tx models.PendingTransaction) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, ok := m.Pending[address]
+	if !ok {
+		return false, ErrNotSubscribed
+	}
+	for _, p := range pending {
+		if p.Hash == tx.Hash {
+			return false, nil
+		}
+	}
+	tx.Transaction = tx.Transaction.ForSubscriber(address)
+	m.Pending[address] = append(pending, tx)
+	return true, nil
+}
+
+// ResolvePending settles pending transactions against the transactions of
+// a newly mined block: a pending transaction that was mined becomes Mined,
+// one whose sender and nonce were used by a different mined transaction
+// becomes Replaced. It returns how many were settled.
+func (m *MemoryDb) ResolvePending(ctx context.Context, mined []models.Transaction) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type slot struct {
+		from  models.Address
+		nonce string
+	}
+	hashes := make(map[string]models.Transaction, len(mined))
+	nonces := make(map[slot]models.Transaction, len(mined))
+	for _, tx := range mined {
+		hashes[tx.Hash] = tx
+		if tx.Nonce != nil {
+			nonces[slot{tx.From, tx.Nonce.String()}] = tx
+		}
+	}
+
+	settled := 0
+	for _, pending := range m.Pending {
+		for i := range pending {
+			p := &pending[i]
+			if p.State != models.Pending {
+				continue
+			}
+			if tx, ok := hashes[p.Hash]; ok {
+				p.State = models.Mined
+				p.BlockNumber = tx.BlockNumber
+				settled++
+				continue
+			}
+			if p.Nonce == nil {
+				continue
+			}
+			if tx, ok := nonces[slot{p.From, p.Nonce.String()}]; ok {
+				p.State = models.Replaced
+				p.ReplacedBy = tx.Hash
+				p.BlockNumber = tx.BlockNumber
+				settled++
+			}
+		}
+	}
+	return settled, nil
+}
+
+// ExpirePending marks transactions still pending that were first seen
+// before seenBefore as Dropped and returns how many were.
+func (m *MemoryDb) ExpirePending(ctx context.Context, seenBefore time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dropped := 0
+	for _, pending := range m.Pending {
+		for i := range pending {
+			if pending[i].State == models.Pending && pending[i].FirstSeen.Before(seenBefore) {
+				pending[i].State = models.Dropped
+				dropped++
+			}
+		}
+	}
+	return dropped, nil
+}
+
+func blockNumberOf(n *big.Int) int64 {
+	if n == nil {
+		return 0
+	}
+	return n.Int64()
+}
+
+// RollbackTxns removes every stored transaction, token, NFT and internal
+// transfer and withdrawal in fromBlock or later, for all subscribers, and
+// returns how many were removed. Pending transactions settled by those
+// blocks become pending again. It is used to discard records from blocks
+// that were orphaned by a chain reorganisation.
+func (m *MemoryDb) RollbackTxns(ctx context.Context, fromBlock int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from := big.NewInt(int64(fromBlock))
+	removed := 0
+	for address, txs := range m.Db {
+		kept := txs[:0]
+		for _, tx := range txs {
+			if tx.BlockNumber != nil && tx.BlockNumber.Cmp(from) >= 0 {
+				removed++
+				continue
+			}
+			kept = append(kept, tx)
+		}
+		m.Db[address] = kept
+		m.reindex(address)
+	}
+	for address, transfers := range m.Transfers {
+		kept := transfers[:0]
+		for _, t := range transfers {
+			if t.BlockNumber != nil && t.BlockNumber.Cmp(from) >= 0 {
+				removed++
+				continue
+			}
+			kept = append(kept, t)
+		}
+		m.Transfers[address] = kept
+	}
+	for address, nfts := range m.NFTs {
+		kept := nfts[:0]
+		for _, n := range nfts {
+			if n.BlockNumber != nil && n.BlockNumber.Cmp(from) >= 0 {
+				removed++
+				continue
+			}
+			kept = append(kept, n)
+		}
+		m.NFTs[address] = kept
+	}
+	for address, internal := range m.Internal {
+		kept := internal[:0]
+		for _, t := range internal {
+			if t.BlockNumber != nil && t.BlockNumber.Cmp(from) >= 0 {
+				removed++
+				continue
+			}
+			kept = append(kept, t)
+		}
+		m.Internal[address] = kept
+	}
+	for address, withdrawals := range m.Withdrawals {
+		kept := withdrawals[:0]
+		for _, w := range withdrawals {
+			if w.BlockNumber != nil && w.BlockNumber.Cmp(from) >= 0 {
+				removed++
+				continue
+			}
+			kept = append(kept, w)
+		}
+		m.Withdrawals[address] = kept
+	}
+	for _, pending := range m.Pending {
+		for i := range pending {
+			p := &pending[i]
+			if (p.State == models.Mined || p.State == models.Replaced) && p.BlockNumber != nil && p.BlockNumber.Cmp(from) >= 0 {
+				p.State = models.Pending
+				p.BlockNumber = nil
+				p.ReplacedBy = ""
+			}
+		}
+	}
+	for block := range m.unfinalized {
+		if block >= int64(fromBlock) {
+			delete(m.unfinalized, block)
+		}
+	}
+	return removed, nil
+}
+
 // DeleteSub removes a subscriber with the specified address from the database.
-func (m *MemoryDb) DeleteSub(ctx context.Context, address string) {
+func (m *MemoryDb) DeleteSub(ctx context.Context, address models.Address) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	address = strings.ToLower(address)
 	delete(m.Db, address)
+	delete(m.Transfers, address)
+	delete(m.NFTs, address)
+	delete(m.Internal, address)
+	delete(m.Withdrawals, address)
+	delete(m.Pending, address)
+	delete(m.subscribers, address)
+	delete(m.txIndexes, address)
 }
 
 // Close deallocates the internal map to free resources.
@@ -97,4 +724,12 @@ func (m *MemoryDb) Close() {
 	defer m.mu.Unlock()
 
 	m.Db = nil
+	m.Transfers = nil
+	m.NFTs = nil
+	m.Internal = nil
+	m.Withdrawals = nil
+	m.Pending = nil
+	m.subscribers = nil
+	m.txIndexes = nil
+	m.unfinalized = nil
 }
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/big"
 	"reflect"
 	"testing"
@@ -13,7 +15,7 @@ func TestMemoryDb(t *testing.T) {
 	db := NewDB()
 	defer db.Close()
 	// Test AddSubscriber
-	address := "0x1234567890abcdef"
+	address := models.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")
 	err := db.AddSubscriber(context.Background(), address)
 	if err != nil {
 		t.Errorf("AddSubscriber failed: %v", err)
@@ -21,8 +23,8 @@ func TestMemoryDb(t *testing.T) {
 
 	// Try adding the same address again
 	err = db.AddSubscriber(context.Background(), address)
-	if err == nil {
-		t.Error("AddSubscriber should return an error for existing address, but it did not")
+	if !errors.Is(err, ErrAlreadySubscribed) {
+		t.Errorf("AddSubscriber for existing address: got %v, want ErrAlreadySubscribed", err)
 	}
 
 	// Test CheckTxns for existing address
@@ -38,10 +40,11 @@ func TestMemoryDb(t *testing.T) {
 	}
 
 	// Test SaveTxns
-	newTxs := map[string][]models.Transaction{
+	other := models.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd")
+	newTxs := map[models.Address][]models.Transaction{
 		address: {
-			{Hash: "tx1", From: "0xabcdef", To: "0x123456", Value: big.NewInt(100)},
-			{Hash: "tx2", From: "0x123456", To: "0xabcdef", Value: big.NewInt(50)},
+			{Hash: "tx1", From: other, To: address, Value: big.NewInt(100)},
+			{Hash: "tx2", From: address, To: other, Value: big.NewInt(50)},
 		},
 	}
 	err = db.SaveTxns(context.Background(), newTxs)
@@ -52,8 +55,8 @@ func TestMemoryDb(t *testing.T) {
 	// Test GetTxns for existing address after saving transactions
 	transactions, err = db.GetTxns(context.Background(), address)
 	expectedTransactions := []models.Transaction{
-		{Hash: "tx1", From: "0xabcdef", To: "0x123456", Value: big.NewInt(100)},
-		{Hash: "tx2", From: "0x123456", To: "0xabcdef", Value: big.NewInt(50)},
+		{Hash: "tx1", From: other, To: address, Value: big.NewInt(100), Direction: models.DirectionIn, Counterparty: other},
+		{Hash: "tx2", From: address, To: other, Value: big.NewInt(50), Direction: models.DirectionOut, Counterparty: other},
 	}
 	if err != nil || !reflect.DeepEqual(transactions, expectedTransactions) {
 		t.Errorf("GetTxns failed after saving transactions. Expected: %v, Got: %v", expectedTransactions, transactions)
@@ -66,3 +69,118 @@ func TestMemoryDb(t *testing.T) {
 		t.Errorf("DeleteSub failed. The address should have been deleted, but it still exists")
 	}
 }
+
+func TestSubscribers(t *testing.T) {
+	db := NewDB()
+	defer db.Close()
+	ctx := context.Background()
+	aaa := models.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	bbb := models.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	db.AddSubscriberAt(ctx, bbb, 100)
+	db.AddSubscriber(ctx, aaa)
+	db.SaveTxns(ctx, map[models.Address][]models.Transaction{
+		bbb: {{Hash: "tx1", BlockNumber: big.NewInt(101)}, {Hash: "tx2", BlockNumber: big.NewInt(105)}},
+	})
+	db.SaveTransfers(ctx, map[models.Address][]models.TokenTransfer{
+		bbb: {{TxHash: "tx3", BlockNumber: big.NewInt(107)}},
+	})
+
+	subscribers, err := db.ListSubscribers(ctx)
+	if err != nil || len(subscribers) != 2 || subscribers[0].Address != aaa || subscribers[1].Address != bbb {
+		t.Fatalf("ListSubscribers = %+v, %v", subscribers, err)
+	}
+
+	sub, err := db.GetSubscriber(ctx, bbb)
+	if err != nil {
+		t.Fatalf("GetSubscriber failed: %v", err)
+	}
+	if sub.CreatedAtBlock != 100 || sub.TxCount != 2 || sub.TransferCount != 1 || sub.LastActivityBlock != 107 {
+		t.Errorf("unexpected subscriber summary %+v", sub)
+	}
+
+	// Records for an address that unsubscribed meanwhile are skipped.
+	stranger := models.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+	err = db.SaveTxns(ctx, map[models.Address][]models.Transaction{
+		stranger: {{Hash: "tx4"}},
+		aaa:      {{Hash: "tx5", BlockNumber: big.NewInt(108)}},
+	})
+	if txs, _ := db.GetTxns(ctx, aaa); err != nil || len(txs) != 1 {
+		t.Errorf("SaveTxns with an unknown address = %v, stored %d for a subscriber", err, len(txs))
+	}
+
+	db.DeleteSub(ctx, bbb)
+	if _, err := db.GetSubscriber(ctx, bbb); err == nil {
+		t.Error("GetSubscriber should fail for a deleted subscriber")
+	}
+}
+
+func TestRollbackReopensPending(t *testing.T) {
+	db := NewDB()
+	defer db.Close()
+	ctx := context.Background()
+	me := models.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	db.AddSubscriber(ctx, me)
+
+	for i, hash := range []string{"0xa", "0xb"} {
+		tx := models.PendingTransaction{Transaction: models.Transaction{Hash: hash, From: me, Nonce: big.NewInt(int64(i))}}
+		db.SavePending(ctx, me, tx)
+	}
+	db.ResolvePending(ctx, []models.Transaction{
+		{Hash: "0xa", From: me, Nonce: big.NewInt(0), BlockNumber: big.NewInt(9)},
+		{Hash: "0xc", From: me, Nonce: big.NewInt(1), BlockNumber: big.NewInt(10)},
+	})
+
+	db.RollbackTxns(ctx, 10)
+	pending, _ := db.GetPending(ctx, me)
+	if pending[0].State != models.Mined || pending[0].BlockNumber.Int64() != 9 {
+		t.Errorf("transaction mined before the rollback changed: %+v", pending[0])
+	}
+	if pending[1].State != models.Pending || pending[1].ReplacedBy != "" || pending[1].BlockNumber != nil {
+		t.Errorf("transaction replaced in an orphaned block not reopened: %+v", pending[1])
+	}
+}
+
+func TestPromoteTxns(t *testing.T) {
+	db := NewDB()
+	defer db.Close()
+	ctx := context.Background()
+	me := models.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	db.AddSubscriber(ctx, me)
+
+	txs := map[models.Address][]models.Transaction{}
+	for block := int64(1); block <= 3; block++ {
+		txs[me] = append(txs[me], models.Transaction{Hash: fmt.Sprintf("0x%d", block), From: me, BlockNumber: big.NewInt(block)})
+	}
+	db.SaveTxns(ctx, txs)
+	db.SaveTransfers(ctx, map[models.Address][]models.TokenTransfer{me: {{TxHash: "0x2", From: me, BlockNumber: big.NewInt(2)}}})
+
+	db.PromoteTxns(ctx, 3, 2, 1)
+	stored, _ := db.GetTxns(ctx, me)
+	for i, want := range []models.ConfirmationStatus{models.Finalized, models.Safe, models.Confirmed} {
+		if stored[i].Confirmation != want {
+			t.Errorf("block %d: got %v, want %v", i+1, stored[i].Confirmation, want)
+		}
+	}
+	if transfers, _ := db.GetTransfers(ctx, me); transfers[0].Confirmation != models.Safe {
+		t.Errorf("transfer in block 2: got %v, want safe", transfers[0].Confirmation)
+	}
+	if _, ok := db.unfinalized[1]; ok || len(db.unfinalized) != 2 {
+		t.Errorf("finalized block still indexed: %v", db.unfinalized)
+	}
+
+	// A backfilled record below the bounds already crossed is promoted as
+	// it is stored.
+	db.MergeTxns(ctx, me, []models.Transaction{{Hash: "0x0", From: me, BlockNumber: big.NewInt(1)}})
+	stored, _ = db.GetTxns(ctx, me)
+	if stored[1].Hash != "0x0" || stored[1].Confirmation != models.Finalized {
+		t.Errorf("backfilled record: got %s %v, want 0x0 finalized", stored[1].Hash, stored[1].Confirmation)
+	}
+
+	db.RollbackTxns(ctx, 3)
+	db.PromoteTxns(ctx, 0, 0, 2)
+	stored, _ = db.GetTxns(ctx, me)
+	if len(stored) != 3 || stored[2].Confirmation != models.Finalized || len(db.unfinalized) != 0 {
+		t.Errorf("after rollback and promotion: %+v, index %v", stored, db.unfinalized)
+	}
+}
@@ -0,0 +1,15 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrAlreadySubscribed is returned when adding an address that is
+	// already a subscriber.
+	ErrAlreadySubscribed = errors.New("[DB-error] address already subscribed")
+	// ErrNotSubscribed is returned when reading or writing records of an
+	// address that is not a subscriber.
+	ErrNotSubscribed = errors.New("[DB-error] address not subscribed")
+	// ErrInvalidCursor is returned by QueryTxns for a cursor it did not
+	// issue.
+	ErrInvalidCursor = errors.New("[DB-error] invalid query cursor")
+)
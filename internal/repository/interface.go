@@ -2,14 +2,46 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/trust-assignment/internal/models"
 )
 
 type DBInterface interface {
-	AddSubscriber(ctx context.Context, address string) error
-	SaveTxns(ctx context.Context, txns map[string][]models.Transaction) error
-	CheckTxns(ctx context.Context, address string) (bool, error)
-	GetTxns(ctx context.Context, address string) ([]models.Transaction, error)
-	DeleteSub(ctx context.Context, address string)
+	AddSubscriber(ctx context.Context, address models.Address) error
+	AddSubscriberAt(ctx context.Context, address models.Address, block int) error
+	CountSubscribers(ctx context.Context) (int, error)
+	ListSubscribers(ctx context.Context) ([]models.Subscriber, error)
+	GetSubscriber(ctx context.Context, address models.Address) (models.Subscriber, error)
+	SaveTxns(ctx context.Context, txns map[models.Address][]models.Transaction) error
+	MergeTxns(ctx context.Context, address models.Address, txns []models.Transaction) error
+	CheckTxns(ctx context.Context, address models.Address) (bool, error)
+	GetTxns(ctx context.Context, address models.Address) ([]models.Transaction, error)
+	QueryTxns(ctx context.Context, address models.Address, q models.TxQuery) (models.TxPage, error)
+	SaveTransfers(ctx context.Context, transfers map[models.Address][]models.TokenTransfer) error
+	MergeTransfers(ctx context.Context, address models.Address, transfers []models.TokenTransfer) error
+	GetTransfers(ctx context.Context, address models.Address) ([]models.TokenTransfer, error)
+	SaveNFTTransfers(ctx context.Context, nfts map[models.Address][]models.NFTTransfer) error
+	MergeNFTTransfers(ctx context.Context, address models.Address, nfts []models.NFTTransfer) error
+	GetNFTTransfers(ctx context.Context, address models.Address) ([]models.NFTTransfer, error)
+	NFTsReceived(ctx context.Context, address models.Address, fromBlock, toBlock int) ([]models.NFTTransfer, error)
+	SaveInternalTransfers(ctx context.Context, internal map[models.Address][]models.InternalTransfer) error
+	MergeInternalTransfers(ctx context.Context, address models.Address, internal []models.InternalTransfer) error
+	GetInternalTransfers(ctx context.Context, address models.Address) ([]models.InternalTransfer, error)
+	SaveWithdrawals(ctx context.Context, withdrawals map[models.Address][]models.WithdrawalTransfer) error
+	MergeWithdrawals(ctx context.Context, address models.Address, withdrawals []models.WithdrawalTransfer) error
+	GetWithdrawals(ctx context.Context, address models.Address) ([]models.WithdrawalTransfer, error)
+	SavePending(ctx context.Context, address models.Address, tx models.PendingTransaction) (bool, error)
+	GetPending(ctx context.Context, address models.Address) ([]models.PendingTransaction, error)
+	ResolvePending(ctx context.Context, mined []models.Transaction) (int, error)
+	ExpirePending(ctx context.Context, seenBefore time.Time) (int, error)
+	RollbackTxns(ctx context.Context, fromBlock int) (int, error)
+	PromoteTxns(ctx context.Context, confirmedUpTo, safeUpTo, finalizedUpTo int) error
+	DeleteSub(ctx context.Context, address models.Address)
+}
+
+// CheckpointRepository persists the scanner's cursor across restarts.
+type CheckpointRepository interface {
+	SaveCheckpoint(ctx context.Context, cp models.Checkpoint) error
+	LoadCheckpoint(ctx context.Context) (models.Checkpoint, error)
 }
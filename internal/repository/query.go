@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+// txIndex holds the positions in MemoryDb.Db of one subscriber's
+// transactions in each direction. Like the transactions themselves, the
+// positions are in block order.
+type txIndex struct {
+	in, out, self []int
+}
+
+func buildTxIndex(txs []models.Transaction) *txIndex {
+	ix := &txIndex{}
+	for i, tx := range txs {
+		ix.add(i, tx)
+	}
+	return ix
+}
+
+// add indexes tx, stored at position pos.
+func (ix *txIndex) add(pos int, tx models.Transaction) {
+	switch tx.Direction {
+	case models.DirectionIn:
+		ix.in = append(ix.in, pos)
+	case models.DirectionOut:
+		ix.out = append(ix.out, pos)
+	case models.DirectionSelf:
+		ix.self = append(ix.self, pos)
+	}
+}
+
+// positions returns the positions of the transactions in direction d, or
+// nil for DirectionAny, meaning every position.
+func (ix *txIndex) positions(d models.Direction) []int {
+	switch d {
+	case models.DirectionIn:
+		return ix.in
+	case models.DirectionOut:
+		return ix.out
+	case models.DirectionSelf:
+		return ix.self
+	}
+	return nil
+}
+
+// reindex rebuilds the index of address after its transactions were
+// reordered or removed. The caller must hold the write lock.
+func (m *MemoryDb) reindex(address models.Address) {
+	m.txIndexes[address] = buildTxIndex(m.Db[address])
+}
+
+// QueryTxns returns a page of the transactions of address that match q.
+// The direction index and the block order of the stored transactions narrow
+// the search to the requested direction and block and time range, so only
+// the returned page is copied.
+func (m *MemoryDb) QueryTxns(ctx context.Context, address models.Address, q models.TxQuery) (models.TxPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	txs, ok := m.Db[address]
+	if !ok {
+		return models.TxPage{}, ErrNotSubscribed
+	}
+
+	// Candidates are addressed by their rank i in the direction's index,
+	// or directly by position when no direction is requested.
+	n := len(txs)
+	at := func(i int) *models.Transaction { return &txs[i] }
+	if q.Direction != models.DirectionAny {
+		list := m.txIndexes[address].positions(q.Direction)
+		n = len(list)
+		at = func(i int) *models.Transaction { return &txs[list[i]] }
+	}
+	search := func(f func(*models.Transaction) bool) int {
+		return sort.Search(n, func(i int) bool { return f(at(i)) })
+	}
+
+	// [lo, hi) are the candidates in the block and time range. Block times
+	// never decrease with the block number, so both are binary searched.
+	lo, hi := 0, n
+	if q.FromBlock > 0 {
+		lo = max(lo, search(func(tx *models.Transaction) bool { return blockOf(*tx) >= int64(q.FromBlock) }))
+	}
+	if q.ToBlock > 0 {
+		hi = min(hi, search(func(tx *models.Transaction) bool { return blockOf(*tx) > int64(q.ToBlock) }))
+	}
+	if !q.Since.IsZero() {
+		lo = max(lo, search(func(tx *models.Transaction) bool { return !tx.Timestamp.Before(q.Since) }))
+	}
+	if !q.Until.IsZero() {
+		hi = min(hi, search(func(tx *models.Transaction) bool { return !tx.Timestamp.Before(q.Until) }))
+	}
+
+	if q.Cursor != "" {
+		block, hash, err := parseCursor(q.Cursor)
+		if err != nil {
+			return models.TxPage{}, err
+		}
+		// Resume after the cursor's transaction. If it was rolled back,
+		// resume after the rest of its block.
+		first := search(func(tx *models.Transaction) bool { return blockOf(*tx) >= block })
+		end := search(func(tx *models.Transaction) bool { return blockOf(*tx) > block })
+		next, prev := end, first-1
+		for i := first; i < end; i++ {
+			if at(i).Hash == hash {
+				next, prev = i+1, i-1
+				break
+			}
+		}
+		if q.Order == models.Descending {
+			hi = min(hi, prev+1)
+		} else {
+			lo = max(lo, next)
+		}
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = models.DefaultQueryLimit
+	}
+	var page models.TxPage
+	visit := func(i int) bool {
+		tx := at(i)
+		if !matches(tx, q) {
+			return true
+		}
+		if len(page.Transactions) == limit {
+			// Another match exists, so there is a next page.
+			last := page.Transactions[limit-1]
+			page.NextCursor = formatCursor(blockOf(last), last.Hash)
+			return false
+		}
+		page.Transactions = append(page.Transactions, *tx)
+		return true
+	}
+	if q.Order == models.Descending {
+		for i := hi - 1; i >= lo && visit(i); i-- {
+		}
+	} else {
+		for i := lo; i < hi && visit(i); i++ {
+		}
+	}
+	return page, nil
+}
+
+// matches applies the filters of q that are not served by an index.
+func matches(tx *models.Transaction, q models.TxQuery) bool {
+	if (!q.Since.IsZero() && tx.Timestamp.Before(q.Since)) || (!q.Until.IsZero() && !tx.Timestamp.Before(q.Until)) {
+		return false
+	}
+	if q.MinValue != nil && (tx.Value == nil || tx.Value.Cmp(q.MinValue) < 0) {
+		return false
+	}
+	if q.MaxValue != nil && tx.Value != nil && tx.Value.Cmp(q.MaxValue) > 0 {
+		return false
+	}
+	if q.Status != nil && tx.Status != *q.Status {
+		return false
+	}
+	return true
+}
+
+// A cursor names the last transaction of a page by block number and hash,
+// so it stays valid while transactions are added or merged in.
+func formatCursor(block int64, hash string) string {
+	return fmt.Sprintf("%d:%s", block, hash)
+}
+
+func parseCursor(cursor string) (int64, string, error) {
+	number, hash, ok := strings.Cut(cursor, ":")
+	block, err := strconv.ParseInt(number, 10, 64)
+	if !ok || err != nil || hash == "" {
+		return 0, "", fmt.Errorf("[%s]: %w", cursor, ErrInvalidCursor)
+	}
+	return block, hash, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+func TestQueryTxns(t *testing.T) {
+	db := NewDB()
+	defer db.Close()
+	ctx := context.Background()
+	me := models.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	other := models.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	db.AddSubscriber(ctx, me)
+
+	// Blocks 1..10 hold one transaction each: odd blocks are incoming,
+	// even blocks outgoing, block 5 is sent to oneself and block 8 failed.
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var txs []models.Transaction
+	for i := 1; i <= 10; i++ {
+		tx := models.Transaction{
+			Hash:        fmt.Sprintf("0xt%d", i),
+			BlockNumber: big.NewInt(int64(i)),
+			Timestamp:   start.Add(time.Duration(i) * time.Hour),
+			From:        other,
+			To:          me,
+			Value:       big.NewInt(int64(i * 100)),
+			Status:      models.TxStatusSuccess,
+		}
+		if i%2 == 0 {
+			tx.From, tx.To = me, other
+		}
+		if i == 5 {
+			tx.From = me
+		}
+		if i == 8 {
+			tx.Status = models.TxStatusFailed
+		}
+		txs = append(txs, tx)
+	}
+	db.SaveTxns(ctx, map[models.Address][]models.Transaction{me: txs[:6]})
+	db.SaveTxns(ctx, map[models.Address][]models.Transaction{me: txs[6:]})
+
+	failed := models.TxStatusFailed
+	tests := []struct {
+		name     string
+		query    models.TxQuery
+		expected string
+	}{
+		{"all", models.TxQuery{}, "1,2,3,4,5,6,7,8,9,10"},
+		{"in", models.TxQuery{Direction: models.DirectionIn}, "1,3,7,9"},
+		{"out", models.TxQuery{Direction: models.DirectionOut}, "2,4,6,8,10"},
+		{"self", models.TxQuery{Direction: models.DirectionSelf}, "5"},
+		{"blocks", models.TxQuery{FromBlock: 3, ToBlock: 6}, "3,4,5,6"},
+		{"out in blocks", models.TxQuery{Direction: models.DirectionOut, FromBlock: 3, ToBlock: 8, Order: models.Descending}, "8,6,4"},
+		{"time", models.TxQuery{Since: start.Add(9 * time.Hour), Until: start.Add(11 * time.Hour)}, "9,10"},
+		{"value", models.TxQuery{MinValue: big.NewInt(250), MaxValue: big.NewInt(700)}, "3,4,5,6,7"},
+		{"status", models.TxQuery{Status: &failed}, "8"},
+		{"limit", models.TxQuery{Order: models.Descending, Limit: 2}, "10,9"},
+	}
+	for _, test := range tests {
+		page, err := db.QueryTxns(ctx, me, test.query)
+		if err != nil {
+			t.Fatalf("%s: QueryTxns failed: %v", test.name, err)
+		}
+		if got := blocksOf(page.Transactions); got != test.expected {
+			t.Errorf("%s: got blocks %s, expected %s", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestQueryTxnsPagination(t *testing.T) {
+	db := NewDB()
+	defer db.Close()
+	ctx := context.Background()
+	me := models.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	db.AddSubscriber(ctx, me)
+	var txs []models.Transaction
+	for i := 1; i <= 7; i++ {
+		txs = append(txs, models.Transaction{Hash: fmt.Sprintf("0xt%d", i), BlockNumber: big.NewInt(int64(i)), To: me})
+	}
+	db.SaveTxns(ctx, map[models.Address][]models.Transaction{me: txs})
+
+	pages := func(q models.TxQuery) []string {
+		var got []string
+		for {
+			page, err := db.QueryTxns(ctx, me, q)
+			if err != nil {
+				t.Fatalf("QueryTxns failed: %v", err)
+			}
+			got = append(got, blocksOf(page.Transactions))
+			if page.NextCursor == "" {
+				return got
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+	if got := strings.Join(pages(models.TxQuery{Limit: 3}), " "); got != "1,2,3 4,5,6 7" {
+		t.Errorf("ascending pages = %s", got)
+	}
+	if got := strings.Join(pages(models.TxQuery{Limit: 3, Order: models.Descending, ToBlock: 6}), " "); got != "6,5,4 3,2,1" {
+		t.Errorf("descending pages = %s", got)
+	}
+
+	// A cursor stays valid while older transactions are merged in.
+	page, _ := db.QueryTxns(ctx, me, models.TxQuery{Limit: 2, FromBlock: 3})
+	db.MergeTxns(ctx, me, []models.Transaction{{Hash: "0xold", BlockNumber: big.NewInt(0), To: me}})
+	page, _ = db.QueryTxns(ctx, me, models.TxQuery{Limit: 2, FromBlock: 3, Cursor: page.NextCursor})
+	if got := blocksOf(page.Transactions); got != "5,6" {
+		t.Errorf("page after merge = %s, expected 5,6", got)
+	}
+
+	if _, err := db.QueryTxns(ctx, me, models.TxQuery{Cursor: "bogus"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("QueryTxns with a bad cursor = %v, expected ErrInvalidCursor", err)
+	}
+}
+
+func blocksOf(txs []models.Transaction) string {
+	blocks := make([]string, len(txs))
+	for i, tx := range txs {
+		blocks[i] = tx.BlockNumber.String()
+	}
+	return strings.Join(blocks, ",")
+}
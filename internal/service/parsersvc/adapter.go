@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"log"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+var _ ParserServiceInterface = (*AssignmentParser)(nil)
+
+// AssignmentParser exposes a ParserService through the assignment's
+// ParserServiceInterface, logging the errors that interface cannot return.
+type AssignmentParser struct {
+	svc *ParserService
+}
+
+// NewAssignmentParser adapts svc to ParserServiceInterface.
+func NewAssignmentParser(svc *ParserService) *AssignmentParser {
+	return &AssignmentParser{svc: svc}
+}
+
+// GetCurrentBlock returns the last parsed block.
+func (a *AssignmentParser) GetCurrentBlock() int {
+	return a.svc.GetCurrentBlock()
+}
+
+// Subscribe adds address to the observer and reports whether it succeeded.
+func (a *AssignmentParser) Subscribe(address string) bool {
+	if err := a.svc.Subscribe(address); err != nil {
+		log.Println("[Parser] Error subscribing address: ", err)
+		return false
+	}
+	return true
+}
+
+// GetTransactions returns the transactions of address, or nil on error.
+func (a *AssignmentParser) GetTransactions(address string) []models.Transaction {
+	txns, err := a.svc.GetTransactions(address)
+	if err != nil {
+		log.Printf("[Parser] Error getting transactions for address %s: %v", address, err)
+		return nil
+	}
+	return txns
+}
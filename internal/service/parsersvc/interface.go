@@ -1,9 +1,10 @@
 package parser
 
-import (
-	"github.com/trust-assignment/internal/models"
-)
+import "github.com/trust-assignment/internal/models"
 
+// ParserServiceInterface is the parser API mandated by the assignment. It
+// reports failures only as false or nil; NewAssignmentParser adapts a
+// ParserService to it.
 type ParserServiceInterface interface {
 	// last parsed block
 	GetCurrentBlock() int
@@ -14,3 +15,32 @@ type ParserServiceInterface interface {
 	// list of inbound or outbound transactions for an address
 	GetTransactions(address string) []models.Transaction
 }
+
+// ParserServiceV2 is the parser API implemented by ParserService. Errors
+// match ErrInvalidAddress, ErrAlreadySubscribed, ErrNotSubscribed or
+// ErrInvalidCursor where applicable.
+type ParserServiceV2 interface {
+	// last parsed block
+	GetCurrentBlock() int
+
+	// add address to observer, backfilling from fromBlock if positive
+	Subscribe(address string) error
+	SubscribeFrom(address string, fromBlock int) error
+
+	// remove address from observer
+	Unsubscribe(address string) error
+
+	// summaries of the observed addresses
+	ListSubscriptions() ([]models.Subscriber, error)
+	GetSubscription(address string) (models.Subscriber, error)
+
+	// records stored for an observed address
+	GetTransactions(address string) ([]models.Transaction, error)
+	QueryTransactions(address string, q models.TxQuery) (models.TxPage, error)
+	GetTokenTransfers(address string) ([]models.TokenTransfer, error)
+	GetNFTTransfers(address string) ([]models.NFTTransfer, error)
+	NFTsReceived(address string, fromBlock, toBlock int) ([]models.NFTTransfer, error)
+	GetInternalTransfers(address string) ([]models.InternalTransfer, error)
+	GetWithdrawals(address string) ([]models.WithdrawalTransfer, error)
+	GetPending(address string) ([]models.PendingTransaction, error)
+}
@@ -2,7 +2,7 @@ package parser
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/trust-assignment/internal/models"
 	repo "github.com/trust-assignment/internal/repository"
@@ -10,15 +10,27 @@ import (
 	"github.com/trust-assignment/pkg/ethclient"
 )
 
+// Errors returned by ParserService, matchable with errors.Is.
+var (
+	ErrInvalidAddress    = models.ErrInvalidAddress
+	ErrAlreadySubscribed = repo.ErrAlreadySubscribed
+	ErrNotSubscribed     = repo.ErrNotSubscribed
+	ErrInvalidCursor     = repo.ErrInvalidCursor
+)
+
+var _ ParserServiceV2 = (*ParserService)(nil)
+
 // ParserService represents a service for parsing and managing transactions.
 type ParserService struct {
 	Db      repo.DBInterface           // Database interface for managing subscribers and transactions
 	Scansvc *scannersvc.ScannerService // Scanner service for retrieving and updating blockchain transactions
 }
 
-func NewParser(ctx context.Context, endpoint string, startAtBlock int) *ParserService {
+// NewParser wires the repository, RPC client and scanner together. opts
+// configure the RPC client, e.g. additional failover endpoints.
+func NewParser(ctx context.Context, endpoint string, startAtBlock int, opts ...ethclient.Option) *ParserService {
 	data := repo.NewDB()
-	ethclt := ethclient.NewEthClient(endpoint)
+	ethclt := ethclient.NewEthClient(endpoint, opts...)
 	scan := scannersvc.NewScanner(ctx, data, ethclt, startAtBlock)
 	return &ParserService{
 		Db:      data,
@@ -26,21 +38,138 @@ func NewParser(ctx context.Context, endpoint string, startAtBlock int) *ParserSe
 	}
 }
 
+// GetCurrentBlock returns the last parsed block.
+func (p *ParserService) GetCurrentBlock() int {
+	return p.Scansvc.GetCurrentBlock()
+}
+
 // Subscribe adds a new subscriber with the given address to the database.
-func (p *ParserService) Subscribe(address string) bool {
-	if err := p.Db.AddSubscriber(context.Background(), address); err != nil {
-		log.Println("[Parser] Error subscribing address: ", err)
-		return false
+func (p *ParserService) Subscribe(address string) error {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	return p.Db.AddSubscriberAt(context.Background(), addr, p.Scansvc.GetCurrentBlock())
+}
+
+// SubscribeFrom subscribes address and, if fromBlock is positive, starts a
+// background backfill of its transactions from fromBlock up to the block the
+// live scanner has reached. Backfill progress is reported by
+// Scansvc.Backfills. If the backfill cannot start the address stays
+// subscribed.
+func (p *ParserService) SubscribeFrom(address string, fromBlock int) error {
+	if err := p.Subscribe(address); err != nil {
+		return err
+	}
+	if fromBlock <= 0 {
+		return nil
+	}
+	if err := p.Scansvc.Backfill(models.HexToAddress(address), fromBlock); err != nil {
+		return fmt.Errorf("[Parser] subscribed %s but could not start backfill: %w", address, err)
+	}
+	return nil
+}
+
+// Unsubscribe stops watching address, cancels its backfill if one is running
+// and discards everything stored for it.
+func (p *ParserService) Unsubscribe(address string) error {
+	sub, err := p.GetSubscription(address)
+	if err != nil {
+		return err
 	}
-	return true
+	p.Scansvc.CancelBackfill(sub.Address)
+	p.Db.DeleteSub(context.Background(), sub.Address)
+	return nil
+}
+
+// ListSubscriptions returns a summary of every subscribed address.
+func (p *ParserService) ListSubscriptions() ([]models.Subscriber, error) {
+	return p.Db.ListSubscribers(context.Background())
+}
+
+// GetSubscription returns a summary of a subscribed address.
+func (p *ParserService) GetSubscription(address string) (models.Subscriber, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return models.Subscriber{}, err
+	}
+	return p.Db.GetSubscriber(context.Background(), addr)
 }
 
 // GetTransactions returns a list of inbound or outbound transactions for an address.
-func (p *ParserService) GetTransactions(address string) []models.Transaction {
-	txns, err := p.Db.GetTxns(context.Background(), address)
+func (p *ParserService) GetTransactions(address string) ([]models.Transaction, error) {
+	addr, err := models.ParseAddress(address)
 	if err != nil {
-		log.Printf("[Parser] Error getting transactions for address %s: %v", address, err)
-		return nil
+		return nil, err
+	}
+	return p.Db.GetTxns(context.Background(), addr)
+}
+
+// QueryTransactions returns a page of the transactions of an address that
+// match q. Pass the page's NextCursor in q.Cursor to fetch the next one.
+func (p *ParserService) QueryTransactions(address string, q models.TxQuery) (models.TxPage, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return models.TxPage{}, err
+	}
+	return p.Db.QueryTxns(context.Background(), addr, q)
+}
+
+// GetTokenTransfers returns the ERC-20 transfers sent from or to an address.
+func (p *ParserService) GetTokenTransfers(address string) ([]models.TokenTransfer, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return p.Db.GetTransfers(context.Background(), addr)
+}
+
+// GetNFTTransfers returns the ERC-721 and ERC-1155 transfers sent from or to
+// an address.
+func (p *ParserService) GetNFTTransfers(address string) ([]models.NFTTransfer, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return p.Db.GetNFTTransfers(context.Background(), addr)
+}
+
+// NFTsReceived returns the NFTs an address received between fromBlock and
+// toBlock, inclusive.
+func (p *ParserService) NFTsReceived(address string, fromBlock, toBlock int) ([]models.NFTTransfer, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return p.Db.NFTsReceived(context.Background(), addr, fromBlock, toBlock)
+}
+
+// GetInternalTransfers returns the ETH contracts sent from or to an address,
+// recorded when the scanner traces blocks.
+func (p *ParserService) GetInternalTransfers(address string) ([]models.InternalTransfer, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return p.Db.GetInternalTransfers(context.Background(), addr)
+}
+
+// GetWithdrawals returns the beacon chain withdrawals credited to an
+// address, with amounts in wei.
+func (p *ParserService) GetWithdrawals(address string) ([]models.WithdrawalTransfer, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return p.Db.GetWithdrawals(context.Background(), addr)
+}
+
+// GetPending returns the mempool transactions seen for an address and
+// whether each was mined, replaced or dropped.
+func (p *ParserService) GetPending(address string) ([]models.PendingTransaction, error) {
+	addr, err := models.ParseAddress(address)
+	if err != nil {
+		return nil, err
 	}
-	return txns
+	return p.Db.GetPending(context.Background(), addr)
 }
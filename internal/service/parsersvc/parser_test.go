@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/trust-assignment/internal/models"
+	repo "github.com/trust-assignment/internal/repository"
+	"github.com/trust-assignment/internal/service/scannersvc"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+const (
+	alice = "0x00000000000000000000000000000000000a11ce"
+	bob   = "0x0000000000000000000000000000000000000b0b"
+)
+
+// newTestParser returns a parser whose scanner has scanned up to block
+// lastScanned. The scanner is never started, so no node is contacted.
+func newTestParser(t *testing.T, lastScanned int) *ParserService {
+	db := repo.NewDB()
+	t.Cleanup(db.Close)
+	client := ethclient.NewEthClient("http://127.0.0.1:0")
+	return &ParserService{
+		Db:      db,
+		Scansvc: scannersvc.NewScanner(context.Background(), db, client, lastScanned+1),
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	p := newTestParser(t, 9)
+
+	for _, address := range []string{"", "0x1234", "0x00000000000000000000000000000000000a11cE"} {
+		if err := p.Subscribe(address); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("Subscribe(%q): got %v, want ErrInvalidAddress", address, err)
+		}
+		if _, err := p.GetTransactions(address); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("GetTransactions(%q): got %v, want ErrInvalidAddress", address, err)
+		}
+	}
+
+	if _, err := p.GetTransactions(alice); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("GetTransactions before Subscribe: got %v, want ErrNotSubscribed", err)
+	}
+	if _, err := p.GetSubscription(alice); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("GetSubscription before Subscribe: got %v, want ErrNotSubscribed", err)
+	}
+	if err := p.Unsubscribe(alice); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("Unsubscribe before Subscribe: got %v, want ErrNotSubscribed", err)
+	}
+
+	if err := p.Subscribe(alice); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := p.Subscribe(alice); !errors.Is(err, ErrAlreadySubscribed) {
+		t.Errorf("second Subscribe: got %v, want ErrAlreadySubscribed", err)
+	}
+	if _, err := p.QueryTransactions(alice, models.TxQuery{Cursor: "bad"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("QueryTransactions with a bad cursor: got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestAssignmentParser(t *testing.T) {
+	p := newTestParser(t, 9)
+	a := NewAssignmentParser(p)
+
+	if got := a.GetCurrentBlock(); got != 9 {
+		t.Errorf("GetCurrentBlock: got %d, want 9", got)
+	}
+	if a.Subscribe("not an address") {
+		t.Error("Subscribe accepted an invalid address")
+	}
+	if txs := a.GetTransactions(alice); txs != nil {
+		t.Errorf("GetTransactions before Subscribe: got %v, want nil", txs)
+	}
+	if !a.Subscribe(alice) {
+		t.Error("Subscribe failed")
+	}
+	if a.Subscribe(alice) {
+		t.Error("second Subscribe succeeded")
+	}
+	if txs := a.GetTransactions(alice); txs == nil || len(txs) != 0 {
+		t.Errorf("GetTransactions after Subscribe: got %#v, want an empty list", txs)
+	}
+}
+
+func TestSubscriptionSummary(t *testing.T) {
+	p := newTestParser(t, 9)
+	ctx := context.Background()
+	if err := p.Subscribe(alice); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := p.Subscribe(bob); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	at := time.Unix(1700000000, 0).UTC()
+	p.Db.SaveTxns(ctx, map[models.Address][]models.Transaction{
+		models.HexToAddress(alice): {
+			{Hash: "0x1", From: models.HexToAddress(alice), To: models.HexToAddress(bob), BlockNumber: big.NewInt(10), Timestamp: at},
+			{Hash: "0x2", From: models.HexToAddress(bob), To: models.HexToAddress(alice), BlockNumber: big.NewInt(12), Timestamp: at.Add(24 * time.Second)},
+		},
+	})
+
+	sub, err := p.GetSubscription(alice)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if sub.Address != models.HexToAddress(alice) || sub.CreatedAtBlock != 9 || sub.CreatedAt.IsZero() {
+		t.Errorf("GetSubscription: got %+v, want alice created at block 9", sub)
+	}
+	if sub.TxCount != 2 || sub.LastActivityBlock != 12 || !sub.LastActivity.Equal(at.Add(24*time.Second)) {
+		t.Errorf("GetSubscription: got %d txs, last activity %d at %v; want 2, 12 at %v",
+			sub.TxCount, sub.LastActivityBlock, sub.LastActivity, at.Add(24*time.Second))
+	}
+
+	subs, err := p.ListSubscriptions()
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if len(subs) != 2 || subs[0].Address != models.HexToAddress(bob) || subs[1] != sub {
+		t.Errorf("ListSubscriptions: got %+v, want bob then %+v", subs, sub)
+	}
+	if subs[0].TxCount != 0 || subs[0].LastActivityBlock != 0 || !subs[0].LastActivity.IsZero() {
+		t.Errorf("ListSubscriptions: bob has activity: %+v", subs[0])
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	p := newTestParser(t, 9)
+	ctx := context.Background()
+	if err := p.Subscribe(alice); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	p.Db.SaveTxns(ctx, map[models.Address][]models.Transaction{
+		models.HexToAddress(alice): {{Hash: "0x1", From: models.HexToAddress(alice), To: models.HexToAddress(bob), BlockNumber: big.NewInt(10)}},
+	})
+
+	if err := p.Unsubscribe(alice); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if _, err := p.GetTransactions(alice); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("GetTransactions after Unsubscribe: got %v, want ErrNotSubscribed", err)
+	}
+	if subs, _ := p.ListSubscriptions(); len(subs) != 0 {
+		t.Errorf("ListSubscriptions after Unsubscribe: got %+v", subs)
+	}
+	if err := p.Unsubscribe(alice); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("second Unsubscribe: got %v, want ErrNotSubscribed", err)
+	}
+
+	// Subscribing again starts from scratch.
+	if err := p.Subscribe(alice); err != nil {
+		t.Fatalf("Subscribe after Unsubscribe: %v", err)
+	}
+	if txs, _ := p.GetTransactions(alice); len(txs) != 0 {
+		t.Errorf("GetTransactions after resubscribing: got %d, want 0", len(txs))
+	}
+}
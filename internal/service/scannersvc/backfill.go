@@ -0,0 +1,219 @@
+package scannersvc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+// backfillBatch is how many historical blocks are requested per JSON-RPC
+// batch while backfilling.
+const backfillBatch = 20
+
+// BackfillStatus reports the progress of a backfill job.
+type BackfillStatus struct {
+	Address models.Address
+	From    int // first block of the historical range
+	To      int // last block of the range, where the live scanner took over
+	Current int // last block backfilled so far
+	Found   int // transactions, internal transfers and withdrawals found so far
+	Tokens  int // token and NFT transfers found so far
+	Done    bool
+	Err     error
+}
+
+// backfillJob scans a historical block range for a single address,
+// independently of the live cursor.
+type backfillJob struct {
+	mu     sync.Mutex
+	status BackfillStatus
+	cancel context.CancelFunc
+}
+
+func (j *backfillJob) snapshot() BackfillStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *backfillJob) update(fn func(*BackfillStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
+
+// Backfill starts a background job that scans blocks from fromBlock up to
+// the live scanner's current block for transactions, token, NFT and internal
+// transfers and withdrawals involving address and merges them into the
+// repository. The live scanner keeps covering every block after that. A job
+// already running for address is replaced.
+func (s *ScannerService) Backfill(address models.Address, fromBlock int) error {
+	to := s.GetCurrentBlock()
+	if to == 0 {
+		// The live scanner has not started yet and will begin at the
+		// head, so backfill up to the current head.
+		head, err := s.Client.BlockNumber(s.ctx)
+		if err != nil {
+			return err
+		}
+		to = head
+	}
+	if fromBlock <= 0 || fromBlock > to {
+		return fmt.Errorf("[Scanner] invalid backfill start block %d, live scanner is at %d", fromBlock, to)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	job := &backfillJob{
+		status: BackfillStatus{Address: address, From: fromBlock, To: to, Current: fromBlock - 1},
+		cancel: cancel,
+	}
+	s.backfillMu.Lock()
+	if old, ok := s.backfills[address]; ok {
+		old.cancel()
+	}
+	s.backfills[address] = job
+	s.backfillMu.Unlock()
+
+	go s.runBackfill(ctx, job)
+	return nil
+}
+
+// CancelBackfill stops the backfill job for address, if any.
+func (s *ScannerService) CancelBackfill(address models.Address) {
+	s.backfillMu.Lock()
+	defer s.backfillMu.Unlock()
+	if job, ok := s.backfills[address]; ok {
+		job.cancel()
+		delete(s.backfills, address)
+	}
+}
+
+// Backfills returns the status of every backfill job started so far.
+func (s *ScannerService) Backfills() []BackfillStatus {
+	s.backfillMu.Lock()
+	defer s.backfillMu.Unlock()
+	statuses := make([]BackfillStatus, 0, len(s.backfills))
+	for _, job := range s.backfills {
+		statuses = append(statuses, job.snapshot())
+	}
+	return statuses
+}
+
+func (s *ScannerService) runBackfill(ctx context.Context, job *backfillJob) {
+	defer job.cancel()
+	status := job.snapshot()
+	fmt.Printf("[Scanner] backfilling %s from block %d to %d\n", status.Address, status.From, status.To)
+
+	for start := status.From; start <= status.To; start += backfillBatch {
+		end := start + backfillBatch - 1
+		if end > status.To {
+			end = status.To
+		}
+		if err := s.backfillRange(ctx, job, status.Address, start, end); err != nil {
+			fmt.Printf("[Scanner] backfill of %s stopped at block %d: %v\n", status.Address, start, err)
+			job.update(func(st *BackfillStatus) { st.Err = err })
+			return
+		}
+	}
+
+	job.update(func(st *BackfillStatus) { st.Done = true })
+	fmt.Printf("[Scanner] backfill of %s complete\n", status.Address)
+}
+
+// backfillRange scans blocks start to end for the records of address,
+// merges them into the repository and reports the progress on job.
+func (s *ScannerService) backfillRange(ctx context.Context, job *backfillJob, address models.Address, start, end int) error {
+	numbers := make([]int, 0, end-start+1)
+	for n := start; n <= end; n++ {
+		numbers = append(numbers, n)
+	}
+
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	blocks, err := s.Client.BlocksByNumber(ctx, numbers)
+	if err != nil {
+		return err
+	}
+
+	// Transactions are stored as unconfirmed; the live scanner upgrades
+	// them the next time it refreshes confirmations.
+	var found []models.Transaction
+	var internal []models.InternalTransfer
+	var withdrawals []models.WithdrawalTransfer
+	for _, block := range blocks {
+		parsed := ParseBlock(block)
+		for _, w := range parseWithdrawals(parsed) {
+			if w.Address == address {
+				withdrawals = append(withdrawals, w)
+			}
+		}
+		for _, tx := range parsed.Transactions {
+			if tx.From == address || tx.To == address {
+				found = append(found, tx)
+			}
+		}
+		calls, err := s.internalCalls(ctx, block)
+		if err != nil {
+			return fmt.Errorf("tracing block %s: %w", block.Number, err)
+		}
+		for _, call := range calls {
+			if t := parseInternalTransfer(call, block); t.From == address || t.To == address {
+				internal = append(internal, t)
+			}
+		}
+	}
+	if len(found) > 0 {
+		if err := s.applyReceipts(ctx, found); err != nil {
+			return err
+		}
+		if err := s.Db.MergeTxns(ctx, address, found); err != nil {
+			return err
+		}
+	}
+
+	logs, err := s.addressLogs(ctx, address, start, end)
+	if err != nil {
+		return err
+	}
+	var transfers []models.TokenTransfer
+	var nfts []models.NFTTransfer
+	for _, l := range logs {
+		if t, ok := parseTransfer(l); ok && (t.From == address || t.To == address) {
+			transfers = append(transfers, t)
+		}
+		for _, n := range parseNFTTransfers(l) {
+			if n.From == address || n.To == address {
+				nfts = append(nfts, n)
+			}
+		}
+	}
+	if len(transfers) > 0 {
+		if err := s.Db.MergeTransfers(ctx, address, transfers); err != nil {
+			return err
+		}
+	}
+	if len(nfts) > 0 {
+		if err := s.Db.MergeNFTTransfers(ctx, address, nfts); err != nil {
+			return err
+		}
+	}
+	if len(internal) > 0 {
+		if err := s.Db.MergeInternalTransfers(ctx, address, internal); err != nil {
+			return err
+		}
+	}
+	if len(withdrawals) > 0 {
+		if err := s.Db.MergeWithdrawals(ctx, address, withdrawals); err != nil {
+			return err
+		}
+	}
+	job.update(func(st *BackfillStatus) {
+		st.Current = end
+		st.Found += len(found) + len(internal) + len(withdrawals)
+		st.Tokens += len(transfers) + len(nfts)
+	})
+	return nil
+}
@@ -0,0 +1,47 @@
+package scannersvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/trust-assignment/internal/models"
+	repo "github.com/trust-assignment/internal/repository"
+)
+
+// Resume moves the scanner to the block after the saved checkpoint. The
+// checkpoint's hash seeds reorg detection, so a reorg that happened while
+// the scanner was down is still rolled back. If no checkpoint was saved the
+// scanner keeps its configured start position and Resume reports false.
+func (s *ScannerService) Resume(ctx context.Context) (bool, error) {
+	if s.Checkpoints == nil {
+		return false, nil
+	}
+	cp, err := s.Checkpoints.LoadCheckpoint(ctx)
+	if errors.Is(err, repo.ErrNoCheckpoint) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.setLastScanned(cp.BlockNumber)
+	s.fromHead = false
+	if cp.BlockHash != "" {
+		s.recent.add(cp.BlockNumber, cp.BlockHash)
+	}
+	fmt.Println("[Scanner] Resuming after checkpoint block: ", cp.BlockNumber)
+	return true, nil
+}
+
+// saveCheckpoint persists the current cursor. Failures are logged rather
+// than returned: the block has already been stored, and the worst outcome
+// of a stale checkpoint is rescanning a few blocks after a restart.
+func (s *ScannerService) saveCheckpoint(ctx context.Context, hash string) {
+	if s.Checkpoints == nil {
+		return
+	}
+	cp := models.Checkpoint{BlockNumber: s.lastScannedBlock, BlockHash: hash}
+	if err := s.Checkpoints.SaveCheckpoint(ctx, cp); err != nil {
+		fmt.Println("[Scanner] Error saving checkpoint: ", err)
+	}
+}
@@ -0,0 +1,78 @@
+package scannersvc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trust-assignment/internal/models"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// scanTarget returns the highest block the scanner may scan given the
+// current head: Confirmations blocks below it, and no further than the
+// safe or finalized block when the scanner follows one of those tags.
+func (s *ScannerService) scanTarget(ctx context.Context, head int) (int, error) {
+	target := head - s.Confirmations
+	if s.Follow == ethclient.TagSafe || s.Follow == ethclient.TagFinalized {
+		tagged, err := s.tagNumber(ctx, s.Follow)
+		if err != nil {
+			return 0, err
+		}
+		if tagged < target {
+			target = tagged
+		}
+	}
+	if target < 0 {
+		return 0, nil
+	}
+	return target, nil
+}
+
+func (s *ScannerService) tagNumber(ctx context.Context, tag string) (int, error) {
+	header, err := s.Client.HeaderByTag(ctx, tag)
+	if err != nil {
+		return 0, fmt.Errorf("[Scanner] Error querying %s block: %w", tag, err)
+	}
+	n, err := strconv.ParseInt(strings.TrimPrefix(header.Number, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("[Scanner] Error parsing %s block number: %v", tag, err)
+	}
+	return int(n), nil
+}
+
+// statusFor returns the confirmation status of a block given the latest head
+// and the last known safe and finalized blocks.
+func (s *ScannerService) statusFor(block, head int) models.ConfirmationStatus {
+	switch {
+	case s.finalizedBlock > 0 && block <= s.finalizedBlock:
+		return models.Finalized
+	case s.safeBlock > 0 && block <= s.safeBlock:
+		return models.Safe
+	case head-block >= s.Confirmations:
+		return models.Confirmed
+	}
+	return models.Unconfirmed
+}
+
+// refreshConfirmations fetches the latest, safe and finalized blocks and
+// upgrades the confirmation status of stored transactions accordingly.
+// Nodes that do not know the safe and finalized tags only get depth-based
+// confirmations.
+func (s *ScannerService) refreshConfirmations(ctx context.Context) error {
+	head, err := s.Client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if safe, err := s.tagNumber(ctx, ethclient.TagSafe); err == nil {
+		s.safeBlock = safe
+	}
+	if finalized, err := s.tagNumber(ctx, ethclient.TagFinalized); err == nil {
+		s.finalizedBlock = finalized
+	}
+
+	// Only blocks we have scanned can hold stored transactions, but the
+	// bounds may safely exceed lastScannedBlock.
+	return s.Db.PromoteTxns(ctx, head-s.Confirmations, s.safeBlock, s.finalizedBlock)
+}
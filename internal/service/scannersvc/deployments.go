@@ -0,0 +1,46 @@
+package scannersvc
+
+import "github.com/trust-assignment/internal/models"
+
+// DeploymentEvent reports a contract deployed by a subscribed address,
+// either directly or, for a subscribed factory contract, from within a
+// transaction. Factory deployments are only seen when Traces is enabled.
+type DeploymentEvent struct {
+	Deployer    models.Address // subscribed address that created the contract
+	Contract    models.Address // address of the new contract
+	TxHash      string
+	BlockNumber int
+	Factory     bool // created by a contract call rather than a deployment transaction
+}
+
+// Deployments returns a channel on which contract deployments by
+// subscribers are reported. Events are dropped if nobody is receiving.
+func (s *ScannerService) Deployments() <-chan DeploymentEvent {
+	return s.deployments
+}
+
+// notifyDeployments reports the deployments among the transactions and
+// internal transfers stored for the subscribers of block number.
+func (s *ScannerService) notifyDeployments(number int, txs map[models.Address][]models.Transaction, internal map[models.Address][]models.InternalTransfer) {
+	for address, list := range txs {
+		for _, tx := range list {
+			if tx.Kind == models.TxKindDeployment && tx.From == address {
+				s.notifyDeployment(DeploymentEvent{Deployer: tx.From, Contract: tx.ContractAddress, TxHash: tx.Hash, BlockNumber: number})
+			}
+		}
+	}
+	for address, list := range internal {
+		for _, t := range list {
+			if t.Type == "create" && t.From == address {
+				s.notifyDeployment(DeploymentEvent{Deployer: t.From, Contract: t.To, TxHash: t.TxHash, BlockNumber: number, Factory: true})
+			}
+		}
+	}
+}
+
+func (s *ScannerService) notifyDeployment(event DeploymentEvent) {
+	select {
+	case s.deployments <- event:
+	default:
+	}
+}
@@ -1,12 +1,17 @@
 package scannersvc
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type ScannerServiceInterface interface {
 	// Run starts the block scanning process.In case of no pending
 	// blocks to be scanned it will return 0.
-	Run() (int, error)
+	Run(ctx context.Context) (int, error)
 	StartScan(interval time.Duration)
+	// Done is closed once the scan goroutine has stopped.
+	Done() <-chan struct{}
 	// GetCurrentBlock returns the last scanned block.
 	GetCurrentBlock() int
 }
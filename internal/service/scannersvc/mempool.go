@@ -0,0 +1,199 @@
+package scannersvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/trust-assignment/internal/models"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// DefaultPendingTTL is how long a pending transaction may go unmined before
+// it is considered dropped, when PendingTTL is not set.
+const DefaultPendingTTL = 3 * time.Hour
+
+// pendingExpiryInterval is how often stale pending transactions are marked
+// dropped.
+const pendingExpiryInterval = time.Minute
+
+// WatchPending records mempool transactions for subscribers as they are
+// announced by sub. Only hashes are announced by some nodes; their bodies
+// are fetched with eth_getTransactionByHash. Pending transactions are
+// settled by the scanner as blocks are committed.
+func (s *ScannerService) WatchPending(sub *ethclient.PendingTxSubscription) {
+	s.mempool.Store(true)
+	go func() {
+		expiry := time.NewTicker(pendingExpiryInterval)
+		defer expiry.Stop()
+		txs := sub.Transactions()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-expiry.C:
+				s.expirePending(s.ctx)
+			case tx, ok := <-txs:
+				if !ok {
+					return
+				}
+				if tx.From == "" {
+					full, err := s.Client.TransactionByHash(s.ctx, tx.Hash)
+					if err != nil {
+						// Usually mined or dropped already.
+						continue
+					}
+					tx = full
+				}
+				s.recordPending(s.ctx, tx)
+			}
+		}
+	}()
+}
+
+// PollTxPool records mempool transactions for subscribers by polling
+// txpool_content at the given interval, for nodes without WebSocket
+// support. It stops if the node does not implement txpool_content.
+func (s *ScannerService) PollTxPool(interval time.Duration) {
+	s.mempool.Store(true)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			content, err := s.Client.TxPoolContent(s.ctx)
+			if errors.Is(err, ethclient.ErrMethodNotFound) {
+				fmt.Println("[Scanner] txpool_content not supported, mempool polling stopped")
+				return
+			}
+			if err != nil {
+				fmt.Println("[Scanner] Error polling mempool: ", err)
+				continue
+			}
+			for _, byNonce := range content.Pending {
+				for _, tx := range byNonce {
+					s.recordPending(s.ctx, tx)
+				}
+			}
+			s.expirePending(s.ctx)
+		}
+	}()
+}
+
+// recordPending stores tx for the subscribers it is sent from or to.
+func (s *ScannerService) recordPending(ctx context.Context, tx *ethclient.Transaction) {
+	if tx.BlockHash != "" && tx.BlockNumber != "" {
+		// Already mined; the scanner picks it up.
+		return
+	}
+	pending := models.PendingTransaction{
+		Transaction: ParseTx(*tx),
+		State:       models.Pending,
+		FirstSeen:   time.Now(),
+	}
+	pending.BlockNumber = nil
+	added := false
+	for _, address := range []models.Address{pending.From, pending.To} {
+		if address.IsZero() {
+			continue
+		}
+		if ok, _ := s.Db.CheckTxns(ctx, address); !ok {
+			continue
+		}
+		if ok, _ := s.Db.SavePending(ctx, address, pending); ok {
+			fmt.Printf("[Scanner] pending transaction %s for %s\n", tx.Hash, address)
+			added = true
+		}
+		if pending.To == pending.From {
+			break
+		}
+	}
+	if added {
+		// The announcement may arrive after the block that mined or
+		// replaced the transaction was committed.
+		if _, err := s.Db.ResolvePending(ctx, s.mined.transactions()); err != nil {
+			fmt.Println("[Scanner] Error settling pending transactions: ", err)
+		}
+	}
+}
+
+// resolvePending settles pending transactions that block mined or
+// replaced. It does nothing unless the mempool is being watched.
+func (s *ScannerService) resolvePending(ctx context.Context, block *ethclient.Block) {
+	if !s.mempool.Load() {
+		return
+	}
+	number := decodeHexString(block.Number)
+	mined := make([]models.Transaction, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		mined[i] = models.Transaction{
+			Hash:        tx.Hash,
+			BlockNumber: number,
+			From:        models.HexToAddress(tx.From),
+			Nonce:       decodeHexString(tx.Nonce),
+		}
+	}
+	// Remember the block before settling, so that a transaction recorded
+	// concurrently is settled by one or the other.
+	s.mined.add(int(number.Int64()), mined)
+	if _, err := s.Db.ResolvePending(ctx, mined); err != nil {
+		fmt.Println("[Scanner] Error settling pending transactions: ", err)
+	}
+}
+
+func (s *ScannerService) expirePending(ctx context.Context) {
+	ttl := s.PendingTTL
+	if ttl <= 0 {
+		ttl = DefaultPendingTTL
+	}
+	if _, err := s.Db.ExpirePending(ctx, time.Now().Add(-ttl)); err != nil {
+		fmt.Println("[Scanner] Error expiring pending transactions: ", err)
+	}
+}
+
+// minedWindow keeps the transactions of the most recently committed blocks,
+// to settle pending transactions announced after their block. It is shared
+// by the scanning and mempool goroutines.
+type minedWindow struct {
+	mu     sync.Mutex
+	size   int
+	blocks map[int][]models.Transaction
+}
+
+func newMinedWindow(size int) *minedWindow {
+	return &minedWindow{size: size, blocks: make(map[int][]models.Transaction)}
+}
+
+func (w *minedWindow) add(number int, txs []models.Transaction) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.blocks[number] = txs
+	delete(w.blocks, number-w.size)
+}
+
+// truncate forgets every block after number.
+func (w *minedWindow) truncate(number int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for n := range w.blocks {
+		if n > number {
+			delete(w.blocks, n)
+		}
+	}
+}
+
+func (w *minedWindow) transactions() []models.Transaction {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var txs []models.Transaction
+	for _, block := range w.blocks {
+		txs = append(txs, block...)
+	}
+	return txs
+}
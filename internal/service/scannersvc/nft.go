@@ -0,0 +1,127 @@
+package scannersvc
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/trust-assignment/internal/models"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// parseNFTTransfers decodes an ERC-721 Transfer or an ERC-1155
+// TransferSingle or TransferBatch log. A batch yields one transfer per
+// token id. Any other log, including malformed ones, yields nothing.
+func parseNFTTransfers(l ethclient.Log) []models.NFTTransfer {
+	if len(l.Topics) == 0 || l.Removed {
+		return nil
+	}
+	base := models.NFTTransfer{
+		Contract:    models.HexToAddress(l.Address),
+		TxHash:      l.TransactionHash,
+		BlockNumber: decodeHexString(l.BlockNumber),
+		BlockHash:   l.BlockHash,
+		LogIndex:    decodeHexString(l.LogIndex).Uint64(),
+	}
+	words := abiWords(l.Data)
+
+	switch strings.ToLower(l.Topics[0]) {
+	case TransferTopic:
+		// ERC-20 Transfer has the same signature with the amount in the
+		// data; ERC-721 indexes the token id instead.
+		if len(l.Topics) != 4 {
+			return nil
+		}
+		base.Standard = models.ERC721
+		base.From = topicAddress(l.Topics[1])
+		base.To = topicAddress(l.Topics[2])
+		base.TokenID = decodeHexString(l.Topics[3])
+		base.Amount = big.NewInt(1)
+		return []models.NFTTransfer{base}
+
+	case TransferSingleTopic:
+		if len(l.Topics) != 4 || len(words) != 2 {
+			return nil
+		}
+		base.Standard = models.ERC1155
+		base.Operator = topicAddress(l.Topics[1])
+		base.From = topicAddress(l.Topics[2])
+		base.To = topicAddress(l.Topics[3])
+		base.TokenID = words[0]
+		base.Amount = words[1]
+		return []models.NFTTransfer{base}
+
+	case TransferBatchTopic:
+		if len(l.Topics) != 4 || len(words) < 2 {
+			return nil
+		}
+		ids, ok := abiUintArray(words, words[0])
+		if !ok {
+			return nil
+		}
+		amounts, ok := abiUintArray(words, words[1])
+		if !ok || len(amounts) != len(ids) {
+			return nil
+		}
+		base.Standard = models.ERC1155
+		base.Operator = topicAddress(l.Topics[1])
+		base.From = topicAddress(l.Topics[2])
+		base.To = topicAddress(l.Topics[3])
+		transfers := make([]models.NFTTransfer, len(ids))
+		for i := range ids {
+			transfers[i] = base
+			transfers[i].TokenID = ids[i]
+			transfers[i].Amount = amounts[i]
+			transfers[i].BatchIndex = i
+		}
+		return transfers
+	}
+	return nil
+}
+
+// abiWords splits ABI-encoded log data into 32-byte words. Trailing bytes
+// that do not fill a word are ignored.
+func abiWords(data string) []*big.Int {
+	data = strings.TrimPrefix(data, "0x")
+	words := make([]*big.Int, 0, len(data)/64)
+	for i := 0; i+64 <= len(data); i += 64 {
+		words = append(words, decodeHexString(data[i:i+64]))
+	}
+	return words
+}
+
+// abiUintArray decodes the uint256[] stored at the given byte offset of the
+// ABI-encoded data split into words.
+func abiUintArray(words []*big.Int, offset *big.Int) ([]*big.Int, bool) {
+	if !offset.IsInt64() || offset.Int64()%32 != 0 {
+		return nil, false
+	}
+	at := int(offset.Int64() / 32)
+	if at >= len(words) || !words[at].IsInt64() {
+		return nil, false
+	}
+	n := int(words[at].Int64())
+	if n < 0 || n > len(words) || at+1+n > len(words) {
+		return nil, false
+	}
+	return words[at+1 : at+1+n], true
+}
+
+// PullNFTTransfers decodes the NFT transfers in logs and groups the ones
+// sent from or to a subscribed address by that address.
+func (s *ScannerService) PullNFTTransfers(ctx context.Context, logs []ethclient.Log) map[models.Address][]models.NFTTransfer {
+	result := make(map[models.Address][]models.NFTTransfer)
+	for _, l := range logs {
+		for _, n := range parseNFTTransfers(l) {
+			if ok, _ := s.Db.CheckTxns(ctx, n.From); ok {
+				result[n.From] = append(result[n.From], n)
+			}
+			if n.To != n.From {
+				if ok, _ := s.Db.CheckTxns(ctx, n.To); ok {
+					result[n.To] = append(result[n.To], n)
+				}
+			}
+		}
+	}
+	return result
+}
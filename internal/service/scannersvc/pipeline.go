@@ -0,0 +1,189 @@
+package scannersvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+const (
+	// DefaultConcurrency is the number of blocks fetched in parallel when
+	// none is configured.
+	DefaultConcurrency = 4
+	// pipelineRange caps how many blocks a single RunPipeline call scans,
+	// so the head, scan target and confirmations are refreshed regularly
+	// during a long catch-up.
+	pipelineRange = 500
+	// pipelineLookahead is how many blocks, per worker, may be fetched
+	// ahead of the next block to commit.
+	pipelineLookahead = 4
+)
+
+// fetchedBlock is a block fetched by a pipeline worker.
+type fetchedBlock struct {
+	number int
+	block  *blockData
+	err    error
+}
+
+// RunPipeline scans every block between the last scanned block and the
+// scan target, up to a bounded range per call. Blocks are fetched by
+// Concurrency workers, possibly out of order and no faster than the rate
+// set with SetRateLimit, and committed strictly in order so reorg detection
+// and the checkpoint behave exactly as with Run. Like Run it returns the
+// last scanned block, or 0 if there was nothing to scan.
+func (s *ScannerService) RunPipeline(ctx context.Context) (int, error) {
+	headBlock, target, err := s.head(ctx)
+	if err != nil {
+		return 0, err
+	}
+	from := nextBlock(s.lastScannedBlock, target)
+	if from == 0 {
+		return 0, nil
+	}
+	to := target
+	if to-from >= pipelineRange {
+		to = from + pipelineRange - 1
+	}
+
+	workers := s.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if n := to - from + 1; workers > n {
+		workers = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Every dispatched block holds a slot until it is committed, which
+	// bounds how far fetching may run ahead of a slow block.
+	slots := make(chan struct{}, workers*pipelineLookahead)
+	jobs := make(chan int)
+	results := make(chan fetchedBlock, workers)
+
+	go func() {
+		defer close(jobs)
+		for n := from; n <= to; n++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				r := fetchedBlock{number: n}
+				if r.err = s.limiter.Wait(ctx); r.err == nil {
+					r.block, r.err = s.fetchBlock(ctx, n)
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	pending := make(map[int]fetchedBlock)
+	last := 0
+	for next := from; next <= to; {
+		var r fetchedBlock
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			return last, ctx.Err()
+		}
+		pending[r.number] = r
+
+		for p, ok := pending[next]; ok; p, ok = pending[next] {
+			delete(pending, next)
+			<-slots
+			if errors.Is(p.err, ethclient.ErrNotFound) {
+				fmt.Println("[Scanner] Block not available yet: ", next)
+				return last, nil
+			}
+			if p.err != nil {
+				fmt.Println("[Scanner] Error querying block: ", p.err)
+				return last, p.err
+			}
+			scanned, err := s.commitBlock(ctx, next, headBlock, p.block)
+			if err != nil {
+				return last, err
+			}
+			if scanned != next {
+				// Reorg: the scanner was rewound, so the blocks still in
+				// flight belong to a range that has to be fetched again.
+				return scanned, nil
+			}
+			last = scanned
+			next++
+		}
+	}
+	return last, nil
+}
+
+// rateLimiter spaces calls evenly so that no more than a given number start
+// per second. A nil *rateLimiter does not limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the caller may start its next call or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// SetRateLimit caps how many block requests per second the pipeline
+// issues; 0 removes the limit.
+func (s *ScannerService) SetRateLimit(perSecond float64) {
+	s.limiter = newRateLimiter(perSecond)
+}
@@ -0,0 +1,75 @@
+package scannersvc
+
+import (
+	"context"
+
+	"github.com/trust-assignment/internal/models"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// watchedHashes returns the hashes of the transactions sent from or to a
+// subscribed address; only those need receipts.
+func (s *ScannerService) watchedHashes(ctx context.Context, txs []ethclient.Transaction) []string {
+	var hashes []string
+	for _, tx := range txs {
+		if ok, _ := s.Db.CheckTxns(ctx, models.HexToAddress(tx.From)); ok {
+			hashes = append(hashes, tx.Hash)
+			continue
+		}
+		if tx.To == "" {
+			continue
+		}
+		if ok, _ := s.Db.CheckTxns(ctx, models.HexToAddress(tx.To)); ok {
+			hashes = append(hashes, tx.Hash)
+		}
+	}
+	return hashes
+}
+
+// applyReceipts fetches the receipts of txs, which may come from different
+// blocks, in one batch and applies them.
+func (s *ScannerService) applyReceipts(ctx context.Context, txs []models.Transaction) error {
+	hashes := make([]string, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash
+	}
+	receipts, err := s.Client.TransactionReceipts(ctx, hashes)
+	if err != nil {
+		return err
+	}
+	for i := range txs {
+		applyReceipt(&txs[i], receipts[i])
+	}
+	return nil
+}
+
+// applyReceipt copies the execution outcome from receipt onto tx.
+func applyReceipt(tx *models.Transaction, receipt *ethclient.Receipt) {
+	switch receipt.Status {
+	case "0x1":
+		tx.Status = models.TxStatusSuccess
+	case "0x0":
+		tx.Status = models.TxStatusFailed
+	}
+	tx.GasUsed = decodeHexString(receipt.GasUsed)
+	tx.CumulativeGasUsed = decodeHexString(receipt.CumulativeGasUsed)
+	if receipt.EffectiveGasPrice != "" {
+		tx.EffectiveGasPrice = decodeHexString(receipt.EffectiveGasPrice)
+	} else if tx.EffectiveGasPrice == nil {
+		// Receipts from before the London fork have no effective gas
+		// price; the legacy gas price is what was paid.
+		tx.EffectiveGasPrice = tx.GasPrice
+	}
+	if receipt.ContractAddress != "" {
+		tx.ContractAddress = models.HexToAddress(receipt.ContractAddress)
+	}
+	tx.Logs = make([]models.Log, len(receipt.Logs))
+	for i, l := range receipt.Logs {
+		tx.Logs[i] = models.Log{
+			Address:  models.HexToAddress(l.Address),
+			Topics:   l.Topics,
+			Data:     l.Data,
+			LogIndex: decodeHexString(l.LogIndex).Uint64(),
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package scannersvc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// DefaultReorgWindow is how many recent block hashes the scanner remembers
+// to detect reorganisations and find the common ancestor.
+const DefaultReorgWindow = 64
+
+// ReorgEvent describes a chain reorganisation handled by the scanner.
+type ReorgEvent struct {
+	CommonAncestor int      // last block shared by the old and new chain
+	OldHead        int      // last block scanned before the reorg
+	Depth          int      // number of orphaned blocks
+	OrphanedHashes []string // hashes of the orphaned blocks, oldest first
+	RemovedTxns    int      // transactions removed from the repository
+}
+
+// blockWindow keeps the hashes of the most recently scanned blocks.
+type blockWindow struct {
+	size   int
+	hashes map[int]string
+	oldest int
+	newest int
+}
+
+func newBlockWindow(size int) *blockWindow {
+	return &blockWindow{size: size, hashes: make(map[int]string)}
+}
+
+func (w *blockWindow) add(number int, hash string) {
+	if len(w.hashes) == 0 {
+		w.oldest = number
+	}
+	w.hashes[number] = hash
+	w.newest = number
+	for w.newest-w.oldest >= w.size {
+		delete(w.hashes, w.oldest)
+		w.oldest++
+	}
+}
+
+func (w *blockWindow) hash(number int) (string, bool) {
+	h, ok := w.hashes[number]
+	return h, ok
+}
+
+// truncate forgets every block after number.
+func (w *blockWindow) truncate(number int) {
+	for n := w.newest; n > number && n >= w.oldest; n-- {
+		delete(w.hashes, n)
+	}
+	w.newest = number
+	if len(w.hashes) == 0 {
+		w.oldest, w.newest = 0, 0
+	}
+}
+
+// Reorgs returns a channel on which handled reorganisations are reported.
+// Events are dropped if nobody is receiving.
+func (s *ScannerService) Reorgs() <-chan ReorgEvent {
+	return s.reorgs
+}
+
+// detectReorg reports whether block does not extend the last scanned block.
+func (s *ScannerService) detectReorg(block *ethclient.Block, number int) bool {
+	parent, ok := s.recent.hash(number - 1)
+	return ok && parent != block.ParentHash
+}
+
+// rollback walks back from the last scanned block until the remembered hash
+// matches the canonical chain, removes transactions from the orphaned blocks
+// and rewinds the scanner so the canonical blocks are scanned next.
+func (s *ScannerService) rollback(ctx context.Context) (*ReorgEvent, error) {
+	oldHead := s.lastScannedBlock
+	ancestor := oldHead
+	var orphaned []string
+	for ; ancestor >= s.recent.oldest; ancestor-- {
+		known, ok := s.recent.hash(ancestor)
+		if !ok {
+			break
+		}
+		canonical, err := s.Client.BlockByNumber(ctx, ancestor)
+		if err != nil {
+			return nil, fmt.Errorf("[Scanner] Error finding common ancestor: %w", err)
+		}
+		if canonical.Hash == known {
+			break
+		}
+		orphaned = append([]string{known}, orphaned...)
+	}
+	if _, ok := s.recent.hash(ancestor); !ok {
+		fmt.Printf("[Scanner] reorg deeper than the %d block window, rolling back to block %d\n", s.recent.size, ancestor)
+	}
+
+	removed, err := s.Db.RollbackTxns(ctx, ancestor+1)
+	if err != nil {
+		return nil, err
+	}
+	s.recent.truncate(ancestor)
+	s.mined.truncate(ancestor)
+	s.setLastScanned(ancestor)
+	ancestorHash, _ := s.recent.hash(ancestor)
+	s.saveCheckpoint(ctx, ancestorHash)
+
+	event := &ReorgEvent{
+		CommonAncestor: ancestor,
+		OldHead:        oldHead,
+		Depth:          oldHead - ancestor,
+		OrphanedHashes: orphaned,
+		RemovedTxns:    removed,
+	}
+	select {
+	case s.reorgs <- *event:
+	default:
+	}
+	return event, nil
+}
@@ -2,32 +2,83 @@ package scannersvc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/trust-assignment/internal/models"
 	repo "github.com/trust-assignment/internal/repository"
+	"github.com/trust-assignment/pkg/crypto"
 	"github.com/trust-assignment/pkg/ethclient"
 )
 
 type ScannerService struct {
-	ctx              context.Context
-	Db               repo.DBInterface
-	Client           *ethclient.EthClient
+	ctx    context.Context
+	Db     repo.DBInterface
+	Client *ethclient.EthClient
+
+	// Confirmations is how many blocks must be mined on top of a block
+	// before it is scanned.
+	Confirmations int
+	// Follow limits scanning to the node's ethclient.TagSafe or
+	// ethclient.TagFinalized block; the default follows the latest head.
+	Follow string
+	// Concurrency is how many blocks are fetched in parallel while
+	// catching up.
+	Concurrency int
+	// Traces enables tracing every block to record ETH that contracts send
+	// to or receive from subscribers. It has no effect if the node supports
+	// neither debug_traceBlockByNumber nor trace_block.
+	Traces bool
+	// PendingTTL is how long a mempool transaction may stay unmined before
+	// it is marked dropped; DefaultPendingTTL if zero.
+	PendingTTL time.Duration
+	// Checkpoints, when set, persists the cursor after every scanned block
+	// so a restarted scanner can Resume where it stopped.
+	Checkpoints repo.CheckpointRepository
+
+	limiter          *rateLimiter // caps block requests per second, nil for no limit
+	mempool          atomic.Bool  // set once pending transactions are watched
+	cursorMu         sync.RWMutex // guards writes to lastScannedBlock against GetCurrentBlock
 	lastScannedBlock int
+	fromHead         bool         // start at the chain head on the next run
+	safeBlock        int          // last known safe block, 0 if unknown
+	finalizedBlock   int          // last known finalized block, 0 if unknown
+	recent           *blockWindow // hashes of recently scanned blocks, for reorg detection
+	mined            *minedWindow // transactions of recently committed blocks, for the mempool
+	reorgs           chan ReorgEvent
+	deployments      chan DeploymentEvent
+	backfillMu       sync.Mutex
+	backfills        map[models.Address]*backfillJob
 	once             sync.Once
+	done             chan struct{}
 }
 
+// NewScanner returns a scanner that starts at block startAt, or at the chain
+// head if startAt is 0.
 func NewScanner(ctx context.Context, db repo.DBInterface, client *ethclient.EthClient, startAt int) *ScannerService {
 	fmt.Println("[Scanner] Scanner set to start at block: ", startAt)
+	lastScanned := 0
+	if startAt > 0 {
+		lastScanned = startAt - 1
+	}
 	return &ScannerService{
 		ctx:              ctx,
 		Db:               db,
 		Client:           client,
-		lastScannedBlock: startAt,
+		Concurrency:      DefaultConcurrency,
+		lastScannedBlock: lastScanned,
+		fromHead:         startAt <= 0,
+		recent:           newBlockWindow(DefaultReorgWindow),
+		mined:            newMinedWindow(DefaultReorgWindow),
+		reorgs:           make(chan ReorgEvent, 16),
+		deployments:      make(chan DeploymentEvent, 16),
+		backfills:        make(map[models.Address]*backfillJob),
+		done:             make(chan struct{}),
 	}
 }
 
@@ -39,100 +90,373 @@ func (s *ScannerService) StartScan(interval time.Duration) {
 	fmt.Println("[Scanner Service] StartScan method called")
 	s.once.Do(func() {
 		go func() {
+			defer close(s.done)
 			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
 			for {
 				select {
 				case <-s.ctx.Done():
-					ticker.Stop()
 					fmt.Println("[Scanner] stopping blockscan")
 					return
 				case <-ticker.C:
 					ticker.Stop()
-					for scannedBlock, err := s.Run(s.ctx); scannedBlock != 0 || err != nil; scannedBlock, err = s.Run(s.ctx) {
-						if err != nil {
-							fmt.Println(fmt.Errorf("[Scanner] error scanning block: %s", err))
-							continue
-						}
+					if !s.catchUp() {
+						return
 					}
 					ticker.Reset(interval)
-					fmt.Printf("[Scanner] last scanned block %d\n", s.GetCurrentBlock())
 				}
 			}
 		}()
 	})
 }
 
+// StartHeadScan is like StartScan but scans as soon as the node announces a
+// new head instead of waiting for the next tick. While the subscription is
+// disconnected it falls back to polling at the given interval.
+func (s *ScannerService) StartHeadScan(sub *ethclient.HeadSubscription, interval time.Duration) {
+	fmt.Println("[Scanner Service] StartHeadScan method called")
+	s.once.Do(func() {
+		go func() {
+			defer close(s.done)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			heads := sub.Heads()
+			for {
+				select {
+				case <-s.ctx.Done():
+					fmt.Println("[Scanner] stopping blockscan")
+					return
+				case head, ok := <-heads:
+					if !ok {
+						heads = nil
+						continue
+					}
+					fmt.Println("[Scanner] new head", head.Number)
+				case <-ticker.C:
+					if sub.Connected() {
+						continue
+					}
+					fmt.Println("[Scanner] head subscription down, polling")
+				}
+				if !s.catchUp() {
+					return
+				}
+			}
+		}()
+	})
+}
+
+// catchUp scans blocks until the scanner reaches the head. It returns false
+// if scanning was interrupted because the scanner's context is done.
+func (s *ScannerService) catchUp() bool {
+	for scannedBlock, err := s.RunPipeline(s.ctx); scannedBlock != 0 || err != nil; scannedBlock, err = s.RunPipeline(s.ctx) {
+		if err != nil {
+			if isCanceled(s.ctx, err) {
+				fmt.Println("[Scanner] stopping blockscan")
+				return false
+			}
+			fmt.Println(fmt.Errorf("[Scanner] error scanning block: %s", err))
+			if errors.Is(err, ethclient.ErrRateLimited) {
+				// Back off until the next tick instead of
+				// hammering a throttled endpoint.
+				break
+			}
+			continue
+		}
+	}
+	if err := s.refreshConfirmations(s.ctx); err != nil {
+		fmt.Println("[Scanner] Error refreshing confirmations: ", err)
+	}
+	fmt.Printf("[Scanner] last scanned block %d\n", s.GetCurrentBlock())
+	return true
+}
+
+// Done returns a channel that is closed once the goroutine spawned by
+// StartScan or StartHeadScan has returned.
+func (s *ScannerService) Done() <-chan struct{} {
+	return s.done
+}
+
+// isCanceled reports whether err was caused by ctx being cancelled, in which
+// case the scan loop should exit instead of retrying.
+func isCanceled(ctx context.Context, err error) bool {
+	var canceled *ethclient.CanceledError
+	return ctx.Err() != nil || errors.As(err, &canceled)
+}
+
 // Run starts the block scanning process. It will return the number
 // of the last scanned block and an error if any. In case of no pending
 // blocks to be scanned it will return 0.
 func (s *ScannerService) Run(ctx context.Context) (int, error) {
-	headBlock, err := s.Client.BlockNumber() //Step1.  get the current latest block
-	fmt.Println("Headblock", headBlock)
+	headBlock, target, err := s.head(ctx) // Step1. get the current latest block
 	if err != nil {
-		fmt.Println("[Scanner] Error querying head block : ", err)
 		return 0, err
 	}
 
-	nextBlock := nextBlock(s.lastScannedBlock, headBlock) // // Step2. Get the next block
+	nextBlock := nextBlock(s.lastScannedBlock, target) // // Step2. Get the next block
 	fmt.Println("Nextblock", nextBlock)
 	if nextBlock == 0 {
 		return 0, nil
 	}
 
-	txs, err := s.ScanBlock(ctx, nextBlock) // // Step3. Get the transaction of next block
+	block, err := s.fetchBlock(ctx, nextBlock) // Step3. Get the next block
+	if errors.Is(err, ethclient.ErrNotFound) {
+		// The head reported by a load-balanced endpoint can be ahead of
+		// the node that served the block request; try again next tick.
+		fmt.Println("[Scanner] Block not available yet: ", nextBlock)
+		return 0, nil
+	}
 	if err != nil {
-		fmt.Println("[Scanner] Error scanning block: ", err)
+		fmt.Println("[Scanner] Error querying block: ", err)
 		return 0, err
 	}
 
-	s.Db.SaveTxns(ctx, txs)
-	s.lastScannedBlock = nextBlock
+	return s.commitBlock(ctx, nextBlock, headBlock, block)
+}
+
+// head returns the chain head and the highest block the scanner may scan.
+// A scanner configured to start at the head is positioned just below it.
+func (s *ScannerService) head(ctx context.Context) (int, int, error) {
+	headBlock, err := s.Client.BlockNumber(ctx)
+	fmt.Println("Headblock", headBlock)
+	if err != nil {
+		fmt.Println("[Scanner] Error querying head block : ", err)
+		return 0, 0, err
+	}
+
+	target, err := s.scanTarget(ctx, headBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if s.fromHead && target > 0 {
+		s.setLastScanned(target - 1)
+		s.fromHead = false
+	}
+	return headBlock, target, nil
+}
+
+// commitBlock stores the subscribers' transactions, token, NFT and internal
+// transfers and withdrawals from block, which must
+// be the block right after the last scanned one, and advances the cursor.
+// If block does not extend the scanned chain it rolls back to the common
+// ancestor instead. It returns the new last scanned block.
+func (s *ScannerService) commitBlock(ctx context.Context, number, headBlock int, block *blockData) (int, error) {
+	if s.detectReorg(block.Block, number) { // Step4. Make sure the block extends the chain we scanned
+		event, err := s.rollback(ctx)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Printf("[Scanner] reorg detected at block %d: rolled back %d blocks to %d, removed %d transactions\n",
+			number, event.Depth, event.CommonAncestor, event.RemovedTxns)
+		// The canonical blocks after the common ancestor are rescanned by
+		// the following runs.
+		return event.CommonAncestor, nil
+	}
+
+	if err := s.storeBlock(ctx, number, headBlock, block); err != nil {
+		// Drop whatever part of the block was stored, so that the retry
+		// does not store it twice. The cursor stays where it was.
+		s.Db.RollbackTxns(ctx, number)
+		fmt.Printf("[Scanner] Error storing block %d: %v\n", number, err)
+		return 0, err
+	}
+	s.resolvePending(ctx, block.Block)
+	s.recent.add(number, block.Hash)
+	s.setLastScanned(number)
+	s.saveCheckpoint(ctx, block.Hash)
 
 	return s.lastScannedBlock, nil
 }
 
+// storeBlock saves the subscribers' records from block with the
+// confirmation status of its depth and, once all are saved, reports the
+// deployments among them.
+func (s *ScannerService) storeBlock(ctx context.Context, number, headBlock int, block *blockData) error {
+	status := s.statusFor(number, headBlock)
+	txs := s.processBlock(ctx, block) // Step5. Get the transactions of the block
+	stamp(txs, status, func(tx *models.Transaction) *models.ConfirmationStatus { return &tx.Confirmation })
+	if err := s.Db.SaveTxns(ctx, txs); err != nil {
+		return err
+	}
+	transfers := s.PullTransfers(ctx, block.logs)
+	stamp(transfers, status, func(t *models.TokenTransfer) *models.ConfirmationStatus { return &t.Confirmation })
+	if err := s.Db.SaveTransfers(ctx, transfers); err != nil {
+		return err
+	}
+	nfts := s.PullNFTTransfers(ctx, block.logs)
+	stamp(nfts, status, func(n *models.NFTTransfer) *models.ConfirmationStatus { return &n.Confirmation })
+	if err := s.Db.SaveNFTTransfers(ctx, nfts); err != nil {
+		return err
+	}
+	internal := s.PullInternalTransfers(ctx, block.Block, block.internal)
+	stamp(internal, status, func(t *models.InternalTransfer) *models.ConfirmationStatus { return &t.Confirmation })
+	if err := s.Db.SaveInternalTransfers(ctx, internal); err != nil {
+		return err
+	}
+	withdrawals := s.PullWithdrawals(ctx, block.parsed)
+	stamp(withdrawals, status, func(w *models.WithdrawalTransfer) *models.ConfirmationStatus { return &w.Confirmation })
+	if err := s.Db.SaveWithdrawals(ctx, withdrawals); err != nil {
+		return err
+	}
+	s.notifyDeployments(number, txs, internal)
+	return nil
+}
+
+// stamp sets every record in records to status. field returns a record's
+// confirmation status.
+func stamp[T any](records map[models.Address][]T, status models.ConfirmationStatus, field func(*T) *models.ConfirmationStatus) {
+	for _, list := range records {
+		for i := range list {
+			*field(&list[i]) = status
+		}
+	}
+}
+
 func nextBlock(lastScannedBlock, headBlock int) int {
-	if lastScannedBlock == headBlock {
+	if lastScannedBlock >= headBlock {
 		return 0
 	}
-	if lastScannedBlock == 0 {
-		return headBlock
-	}
 	next := lastScannedBlock + 1
 	return next
 }
 
-func (s *ScannerService) ScanBlock(ctx context.Context, blockNumber int) (map[string][]models.Transaction, error) {
-	block, err := s.Client.BlockByNumber(blockNumber) // Step1. Get All the transactions of block number
+func (s *ScannerService) ScanBlock(ctx context.Context, blockNumber int) (map[models.Address][]models.Transaction, error) {
+	block, err := s.fetchBlock(ctx, blockNumber) // Step1. Get All the transactions of block number
 	if err != nil {
 		fmt.Println("[Scanner] Error querying block: ", err)
 		return nil, err
 	}
-	fmt.Println("[Scanner] Block Details", block.Number)
-	fmt.Println("[Scanner] Block HAsh", block.Hash)
-	newTxs := s.Pull(ctx, parseTxs(block.Transactions))
+	newTxs := s.processBlock(ctx, block)
 	if len(newTxs) == 0 {
-		return nil, err
+		return nil, nil
 	}
 	return newTxs, nil
 }
 
-// parseTxs converts a list of ethclient.Transaction into a list of
-// service.Transaction.
-func parseTxs(txs []ethclient.Transaction) []models.Transaction {
-	transactions := make([]models.Transaction, len(txs))
-	for i, v := range txs {
+// blockData is a block together with the receipts of its transactions
+// that involve subscribed addresses, keyed by transaction hash, its token
+// transfer logs and, when tracing, its internal calls.
+type blockData struct {
+	*ethclient.Block
+	parsed   models.Block
+	receipts map[string]*ethclient.Receipt
+	logs     []ethclient.Log
+	internal []ethclient.InternalCall
+}
+
+// fetchBlock fetches a block, the receipts its subscribers need, its token
+// transfer logs and, when tracing, its internal calls.
+func (s *ScannerService) fetchBlock(ctx context.Context, number int) (*blockData, error) {
+	block, err := s.Client.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := s.Client.Receipts(ctx, number, s.watchedHashes(ctx, block.Transactions))
+	if err != nil {
+		return nil, fmt.Errorf("[Scanner] Error fetching receipts for block %d: %w", number, err)
+	}
+	// Like the receipts, the logs are only needed for subscribers.
+	var logs []ethclient.Log
+	if n, _ := s.Db.CountSubscribers(ctx); n > 0 {
+		logs, err = s.transferLogs(ctx, block.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("[Scanner] Error fetching logs for block %d: %w", number, err)
+		}
+	}
+	internal, err := s.internalCalls(ctx, block)
+	if err != nil {
+		return nil, fmt.Errorf("[Scanner] Error tracing block %d: %w", number, err)
+	}
+	return &blockData{Block: block, parsed: ParseBlock(block), receipts: receipts, logs: logs, internal: internal}, nil
+}
+
+// processBlock extracts the transactions in block that involve subscribed
+// addresses, enriched with their receipts.
+func (s *ScannerService) processBlock(ctx context.Context, block *blockData) map[models.Address][]models.Transaction {
+	fmt.Println("[Scanner] Block Details", block.Number)
+	fmt.Println("[Scanner] Block HAsh", block.Hash)
+	txs := block.parsed.Transactions
+	for i := range txs {
+		if receipt, ok := block.receipts[txs[i].Hash]; ok {
+			applyReceipt(&txs[i], receipt)
+		}
+	}
+	return s.Pull(ctx, txs)
+}
+
+// ParseBlock converts a block returned by the node, with its transactions.
+func ParseBlock(block *ethclient.Block) models.Block {
+	parsed := models.Block{
+		Number:        decodeHexString(block.Number),
+		Hash:          block.Hash,
+		ParentHash:    block.ParentHash,
+		Timestamp:     blockTime(block.Timestamp),
+		FeeRecipient:  models.HexToAddress(block.Miner),
+		GasUsed:       decodeHexString(block.GasUsed),
+		GasLimit:      decodeHexString(block.GasLimit),
+		BaseFeePerGas: optionalHex(block.BaseFeePerGas),
+		BlobGasUsed:   optionalHex(block.BlobGasUsed),
+		ExcessBlobGas: optionalHex(block.ExcessBlobGas),
+		Transactions:  parseTxs(block),
+	}
+	if len(block.Withdrawals) > 0 {
+		parsed.Withdrawals = make([]models.Withdrawal, len(block.Withdrawals))
+		for i, w := range block.Withdrawals {
+			parsed.Withdrawals[i] = models.Withdrawal{
+				Index:          decodeHexString(w.Index).Uint64(),
+				ValidatorIndex: decodeHexString(w.ValidatorIndex).Uint64(),
+				Address:        models.HexToAddress(w.Address),
+				Amount:         decodeHexString(w.Amount),
+			}
+		}
+	}
+	return parsed
+}
+
+// parseTxs converts the transactions of block into a list of
+// models.Transaction, stamped with the block time.
+func parseTxs(block *ethclient.Block) []models.Transaction {
+	baseFee := optionalHex(block.BaseFeePerGas)
+	timestamp := blockTime(block.Timestamp)
+	transactions := make([]models.Transaction, len(block.Transactions))
+	for i, v := range block.Transactions {
 		transactions[i] = ParseTx(v)
+		transactions[i].Timestamp = timestamp
+		transactions[i].EffectiveGasPrice = effectiveGasPrice(transactions[i], baseFee)
 	}
 	return transactions
 }
 
-func (s *ScannerService) Pull(ctx context.Context, txs []models.Transaction) map[string][]models.Transaction {
-	result := make(map[string][]models.Transaction)
+// blockTime converts a hex Unix timestamp. A missing timestamp yields the
+// zero time.
+func blockTime(hexStr string) time.Time {
+	if hexStr == "" {
+		return time.Time{}
+	}
+	return time.Unix(decodeHexString(hexStr).Int64(), 0).UTC()
+}
+
+// optionalHex decodes a quantity that only exists from some fork on, or
+// returns nil if the node did not report it.
+func optionalHex(hexStr string) *big.Int {
+	if hexStr == "" {
+		return nil
+	}
+	return decodeHexString(hexStr)
+}
+
+func (s *ScannerService) Pull(ctx context.Context, txs []models.Transaction) map[models.Address][]models.Transaction {
+	result := make(map[models.Address][]models.Transaction)
 	for _, tx := range txs {
 		if ok, _ := s.Db.CheckTxns(ctx, tx.From); ok {
 			result[tx.From] = append(result[tx.From], tx)
 		}
+		if tx.To.IsZero() || tx.To == tx.From {
+			// Deployments have no recipient; a self-transfer is stored
+			// once.
+			continue
+		}
 		if ok, _ := s.Db.CheckTxns(ctx, tx.To); ok {
 			result[tx.To] = append(result[tx.To], tx)
 		}
@@ -140,26 +464,97 @@ func (s *ScannerService) Pull(ctx context.Context, txs []models.Transaction) map
 	return result
 }
 
+// ParseTx converts a transaction returned by the node. A transaction without
+// a recipient is a contract deployment; the address of the new contract is
+// derived from the sender and nonce until the receipt confirms it.
 func ParseTx(tx ethclient.Transaction) models.Transaction {
-	return models.Transaction{
+	parsed := models.Transaction{
 		ChainID:     decodeHexString(tx.ChainID),
 		BlockNumber: decodeHexString(tx.BlockNumber),
+		BlockHash:   tx.BlockHash,
 		Hash:        tx.Hash,
 		Nonce:       decodeHexString(tx.Nonce),
-		From:        tx.From,
-		To:          tx.To,
+		From:        models.HexToAddress(tx.From),
+		To:          models.HexToAddress(tx.To),
 		Value:       decodeHexString(tx.Value),
 		Gas:         decodeHexString(tx.Gas),
 		GasPrice:    decodeHexString(tx.GasPrice),
 		Input:       tx.Input,
+		Type:        models.TxType(decodeHexString(tx.Type).Int64()),
+	}
+	if tx.To == "" {
+		parsed.Kind = models.TxKindDeployment
+		if address, err := crypto.CreateAddress(tx.From, parsed.Nonce.Uint64()); err == nil {
+			parsed.ContractAddress = models.HexToAddress(address)
+		}
+	}
+
+	if parsed.Type >= models.AccessListTxType {
+		parsed.AccessList = make([]models.AccessListEntry, len(tx.AccessList))
+		for i, entry := range tx.AccessList {
+			parsed.AccessList[i] = models.AccessListEntry{
+				Address:     models.HexToAddress(entry.Address),
+				StorageKeys: entry.StorageKeys,
+			}
+		}
+	}
+	if parsed.Type >= models.DynamicFeeTxType {
+		parsed.MaxFeePerGas = decodeHexString(tx.MaxFeePerGas)
+		parsed.MaxPriorityFeePerGas = decodeHexString(tx.MaxPriorityFeePerGas)
+	}
+	if parsed.Type == models.BlobTxType {
+		parsed.MaxFeePerBlobGas = decodeHexString(tx.MaxFeePerBlobGas)
+		parsed.BlobVersionedHashes = tx.BlobVersionedHashes
+	}
+	if parsed.Type == models.SetCodeTxType {
+		parsed.AuthorizationList = make([]models.Authorization, len(tx.AuthorizationList))
+		for i, auth := range tx.AuthorizationList {
+			parsed.AuthorizationList[i] = models.Authorization{
+				ChainID: decodeHexString(auth.ChainID),
+				Address: models.HexToAddress(auth.Address),
+				Nonce:   decodeHexString(auth.Nonce).Uint64(),
+				YParity: uint8(decodeHexString(auth.YParity).Uint64()),
+				R:       decodeHexString(auth.R),
+				S:       decodeHexString(auth.S),
+			}
+		}
 	}
+	return parsed
+}
+
+// effectiveGasPrice returns the price per gas tx pays in a block with the
+// given base fee: the gas price for legacy and access list transactions, and
+// min(maxFeePerGas, baseFee+maxPriorityFeePerGas) for fee market ones. It
+// returns nil if a fee market transaction's block has no base fee.
+func effectiveGasPrice(tx models.Transaction, baseFee *big.Int) *big.Int {
+	if tx.Type < models.DynamicFeeTxType || tx.MaxFeePerGas == nil {
+		return tx.GasPrice
+	}
+	if baseFee == nil {
+		return nil
+	}
+	price := new(big.Int).Add(baseFee, tx.MaxPriorityFeePerGas)
+	if price.Cmp(tx.MaxFeePerGas) > 0 {
+		price.Set(tx.MaxFeePerGas)
+	}
+	return price
 }
 
 // GetCurrentBlock returns the last scanned block.
 func (s *ScannerService) GetCurrentBlock() int {
+	s.cursorMu.RLock()
+	defer s.cursorMu.RUnlock()
 	return s.lastScannedBlock
 }
 
+// setLastScanned moves the cursor. Only the scanning goroutine writes the
+// cursor, so it may read lastScannedBlock directly.
+func (s *ScannerService) setLastScanned(n int) {
+	s.cursorMu.Lock()
+	defer s.cursorMu.Unlock()
+	s.lastScannedBlock = n
+}
+
 func decodeHexString(hexStr string) *big.Int {
 	hexStr = strings.TrimPrefix(hexStr, "0x")
 
@@ -0,0 +1,927 @@
+package scannersvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trust-assignment/internal/models"
+	repo "github.com/trust-assignment/internal/repository"
+	"github.com/trust-assignment/pkg/crypto"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// fakeNode is an in-memory chain served over JSON-RPC.
+type fakeNode struct {
+	mu        sync.Mutex
+	blocks    map[int]ethclient.Block
+	receipts  map[string]ethclient.Receipt
+	logs      []ethclient.Log
+	traces    map[int][]ethclient.TxTrace // callTracer results, by block
+	pool      []ethclient.Transaction     // pending transactions served by txpool_content
+	head      int
+	safe      int
+	finalized int
+	delay     func(number int) time.Duration // optional per-block response delay
+	calls     map[string]int                 // requests served, by method
+}
+
+func newFakeNode(t *testing.T) (*fakeNode, *ethclient.EthClient) {
+	node := &fakeNode{
+		blocks:   make(map[int]ethclient.Block),
+		receipts: make(map[string]ethclient.Receipt),
+		traces:   make(map[int][]ethclient.TxTrace),
+		calls:    make(map[string]int),
+	}
+	srv := httptest.NewServer(node)
+	t.Cleanup(srv.Close)
+	return node, ethclient.NewEthClient(srv.URL, ethclient.WithRetryPolicy(ethclient.RetryPolicy{MaxAttempts: 1}))
+}
+
+// mine appends a block on top of parent with the given fork tag and
+// transactions, and makes it the head.
+func (n *fakeNode) mine(number int, fork string, txs ...ethclient.Transaction) ethclient.Block {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	hash := fmt.Sprintf("0x%s%d", fork, number)
+	for i := range txs {
+		txs[i].BlockNumber = fmt.Sprintf("0x%x", number)
+		txs[i].BlockHash = hash
+		n.receipts[txs[i].Hash] = ethclient.Receipt{
+			TransactionHash:   txs[i].Hash,
+			BlockNumber:       txs[i].BlockNumber,
+			BlockHash:         hash,
+			Status:            "0x1",
+			GasUsed:           "0x5208",
+			CumulativeGasUsed: "0x5208",
+			EffectiveGasPrice: "0x3b9aca00",
+		}
+	}
+	block := ethclient.Block{
+		Number:       fmt.Sprintf("0x%x", number),
+		Hash:         hash,
+		ParentHash:   n.blocks[number-1].Hash,
+		Timestamp:    fmt.Sprintf("0x%x", 1700000000+12*number),
+		Transactions: txs,
+	}
+	n.blocks[number] = block
+	n.head = number
+	for k := range n.blocks {
+		if k > number {
+			delete(n.blocks, k)
+		}
+	}
+	return block
+}
+
+// emit adds an ERC-20 Transfer log to block number, which must be mined.
+func (n *fakeNode) emit(number int, txHash, token, from, to string, amount int) {
+	n.emitLog(number, txHash, token, fmt.Sprintf("0x%064x", amount),
+		TransferTopic, addressTopic(models.HexToAddress(from)), addressTopic(models.HexToAddress(to)))
+}
+
+// emitLog adds a log to block number, which must be mined.
+func (n *fakeNode) emitLog(number int, txHash, contract, data string, topics ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.logs = append(n.logs, ethclient.Log{
+		Address:         contract,
+		Topics:          topics,
+		Data:            data,
+		BlockNumber:     fmt.Sprintf("0x%x", number),
+		BlockHash:       n.blocks[number].Hash,
+		TransactionHash: txHash,
+		LogIndex:        fmt.Sprintf("0x%x", len(n.logs)),
+	})
+}
+
+// filterLogs applies an eth_getLogs filter object to the emitted logs.
+func (n *fakeNode) filterLogs(filter map[string]interface{}) []ethclient.Log {
+	parse := func(key string) int {
+		v, _ := filter[key].(string)
+		number, _ := strconv.ParseInt(strings.TrimPrefix(v, "0x"), 16, 64)
+		return int(number)
+	}
+	topics, _ := filter["topics"].([]interface{})
+	logs := []ethclient.Log{}
+next:
+	for _, l := range n.logs {
+		if hash, ok := filter["blockHash"].(string); ok {
+			if l.BlockHash != hash {
+				continue
+			}
+		} else {
+			number, _ := strconv.ParseInt(strings.TrimPrefix(l.BlockNumber, "0x"), 16, 64)
+			if int(number) < parse("fromBlock") || int(number) > parse("toBlock") ||
+				n.blocks[int(number)].Hash != l.BlockHash {
+				continue
+			}
+		}
+		for i, position := range topics {
+			options, _ := position.([]interface{})
+			if len(options) == 0 {
+				continue
+			}
+			matched := false
+			for _, option := range options {
+				matched = matched || (i < len(l.Topics) && option == l.Topics[i])
+			}
+			if !matched {
+				continue next
+			}
+		}
+		logs = append(logs, l)
+	}
+	return logs
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		var reqs []ethclient.RequestBody
+		json.Unmarshal(raw, &reqs)
+		resps := make([]map[string]interface{}, len(reqs))
+		for i, req := range reqs {
+			resps[i] = n.answer(req)
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+	var req ethclient.RequestBody
+	json.Unmarshal(raw, &req)
+	json.NewEncoder(w).Encode(n.answer(req))
+}
+
+func (n *fakeNode) answer(req ethclient.RequestBody) map[string]interface{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[req.Method]++
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	params, _ := req.Params.([]interface{})
+	switch req.Method {
+	case "eth_blockNumber":
+		resp["result"] = fmt.Sprintf("0x%x", n.head)
+	case "eth_getBlockByNumber":
+		var number int64
+		switch tag := params[0].(string); tag {
+		case "safe":
+			number = int64(n.safe)
+		case "finalized":
+			number = int64(n.finalized)
+		default:
+			number, _ = strconv.ParseInt(strings.TrimPrefix(tag, "0x"), 16, 64)
+		}
+		if n.delay != nil {
+			d := n.delay(int(number))
+			n.mu.Unlock()
+			time.Sleep(d)
+			n.mu.Lock()
+		}
+		if block, ok := n.blocks[int(number)]; ok {
+			resp["result"] = block
+		} else {
+			resp["result"] = nil
+		}
+	case "eth_getBlockReceipts":
+		number, _ := strconv.ParseInt(strings.TrimPrefix(params[0].(string), "0x"), 16, 64)
+		receipts := []ethclient.Receipt{}
+		for _, tx := range n.blocks[int(number)].Transactions {
+			receipts = append(receipts, n.receipts[tx.Hash])
+		}
+		resp["result"] = receipts
+	case "eth_getTransactionReceipt":
+		if receipt, ok := n.receipts[params[0].(string)]; ok {
+			resp["result"] = receipt
+		} else {
+			resp["result"] = nil
+		}
+	case "debug_traceBlockByNumber":
+		number, _ := strconv.ParseInt(strings.TrimPrefix(params[0].(string), "0x"), 16, 64)
+		resp["result"] = n.traces[int(number)]
+	case "txpool_content":
+		pending := make(map[string]map[string]ethclient.Transaction)
+		for _, tx := range n.pool {
+			if pending[tx.From] == nil {
+				pending[tx.From] = make(map[string]ethclient.Transaction)
+			}
+			pending[tx.From][tx.Nonce] = tx
+		}
+		resp["result"] = map[string]interface{}{"pending": pending, "queued": map[string]interface{}{}}
+	case "eth_getLogs":
+		filter, _ := params[0].(map[string]interface{})
+		resp["result"] = n.filterLogs(filter)
+	default:
+		resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+	return resp
+}
+
+const (
+	alice = "0x00000000000000000000000000000000000a11ce"
+	bob   = "0x0000000000000000000000000000000000000b0b"
+)
+
+func transfer(hash, from, to string) ethclient.Transaction {
+	return ethclient.Transaction{Hash: hash, From: from, To: to, Value: "0x1"}
+}
+
+func TestScannerRollsBackReorg(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xt2", alice, bob))
+	node.mine(3, "a", transfer("0xt3", bob, alice))
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	for {
+		n, err := scanner.Run(context.Background())
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice)); len(txs) != 2 {
+		t.Fatalf("expected 2 transactions before reorg, got %d", len(txs))
+	}
+
+	// Replace blocks 3 and beyond with a fork that drops 0xt3.
+	node.mine(3, "b")
+	node.mine(4, "b", transfer("0xt4", alice, bob))
+
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	select {
+	case event := <-scanner.Reorgs():
+		if event.CommonAncestor != 2 || event.Depth != 1 || event.RemovedTxns != 1 {
+			t.Errorf("unexpected reorg event %+v", event)
+		}
+	default:
+		t.Fatal("expected a reorg event")
+	}
+
+	for n, err := scanner.Run(context.Background()); n != 0; n, err = scanner.Run(context.Background()) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice))
+	var hashes []string
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+	}
+	if strings.Join(hashes, ",") != "0xt2,0xt4" {
+		t.Errorf("expected canonical transactions 0xt2,0xt4, got %v", hashes)
+	}
+	if scanner.GetCurrentBlock() != 4 {
+		t.Errorf("expected scanner at block 4, got %d", scanner.GetCurrentBlock())
+	}
+}
+
+func TestScannerResumesFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(ctx, models.HexToAddress(alice))
+	checkpoints := repo.NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xt2", alice, bob))
+	node.mine(3, "a", transfer("0xt3", bob, alice))
+
+	first := NewScanner(ctx, db, client, 2)
+	first.Checkpoints = checkpoints
+	for n, err := first.Run(ctx); n != 0; n, err = first.Run(ctx) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+
+	// While the scanner is down, block 3 is replaced by a fork that drops
+	// 0xt3 and the chain grows.
+	node.mine(3, "b")
+	node.mine(4, "b", transfer("0xt4", alice, bob))
+
+	// The restarted scanner would start at the head without the checkpoint.
+	restarted := NewScanner(ctx, db, client, 0)
+	restarted.Checkpoints = checkpoints
+	if ok, err := restarted.Resume(ctx); !ok || err != nil {
+		t.Fatalf("Resume = %v, %v", ok, err)
+	}
+	if restarted.GetCurrentBlock() != 3 {
+		t.Fatalf("expected to resume after block 3, got %d", restarted.GetCurrentBlock())
+	}
+
+	// Block 4 does not extend the checkpoint's block 3, so the first Run
+	// rolls back to the common ancestor instead of scanning it.
+	if _, err := restarted.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	select {
+	case event := <-restarted.Reorgs():
+		if event.CommonAncestor != 2 || event.OldHead != 3 || event.RemovedTxns != 1 ||
+			strings.Join(event.OrphanedHashes, ",") != "0xa3" {
+			t.Errorf("unexpected reorg event %+v", event)
+		}
+	default:
+		t.Fatal("expected a reorg event")
+	}
+
+	for n, err := restarted.Run(ctx); n != 0; n, err = restarted.Run(ctx) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	txs, _ := db.GetTxns(ctx, models.HexToAddress(alice))
+	var hashes []string
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+	}
+	if strings.Join(hashes, ",") != "0xt2,0xt4" {
+		t.Errorf("expected canonical transactions 0xt2,0xt4, got %v", hashes)
+	}
+	if cp, _ := checkpoints.LoadCheckpoint(ctx); cp.BlockNumber != 4 || cp.BlockHash != "0xb4" {
+		t.Errorf("expected checkpoint at block 4 (0xb4), got %+v", cp)
+	}
+}
+
+func TestScannerConfirmations(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	for i := 1; i <= 5; i++ {
+		node.mine(i, "a", transfer(fmt.Sprintf("0xt%d", i), alice, bob))
+	}
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	scanner.Confirmations = 2
+	for n, err := scanner.Run(context.Background()); n != 0; n, err = scanner.Run(context.Background()) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if scanner.GetCurrentBlock() != 3 {
+		t.Fatalf("expected scanner to stop 2 blocks below head at 3, got %d", scanner.GetCurrentBlock())
+	}
+
+	node.mu.Lock()
+	node.safe, node.finalized = 3, 2
+	node.mu.Unlock()
+	node.mine(6, "a")
+	if err := scanner.refreshConfirmations(context.Background()); err != nil {
+		t.Fatalf("refreshConfirmations failed: %v", err)
+	}
+
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice))
+	var statuses []string
+	for _, tx := range txs {
+		statuses = append(statuses, tx.Hash+"="+tx.Confirmation.String())
+	}
+	expected := "0xt2=finalized,0xt3=safe"
+	if got := strings.Join(statuses, ","); got != expected {
+		t.Errorf("statuses = %s, expected %s", got, expected)
+	}
+}
+
+func TestRunPipelineCommitsInOrder(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	const blocks = 40
+	for i := 1; i <= blocks; i++ {
+		node.mine(i, "a", transfer(fmt.Sprintf("0xt%d", i), alice, bob))
+	}
+	// Later blocks answer faster, so they arrive before earlier ones.
+	node.delay = func(number int) time.Duration {
+		return time.Duration(blocks-number) * time.Millisecond / 4
+	}
+
+	scanner := NewScanner(context.Background(), db, client, 1)
+	scanner.Concurrency = 8
+	last, err := scanner.RunPipeline(context.Background())
+	if err != nil || last != blocks {
+		t.Fatalf("RunPipeline = %d, %v; expected %d, nil", last, err, blocks)
+	}
+
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice))
+	if len(txs) != blocks {
+		t.Fatalf("expected %d transactions, got %d", blocks, len(txs))
+	}
+	for i, tx := range txs {
+		if expected := fmt.Sprintf("0xt%d", i+1); tx.Hash != expected {
+			t.Fatalf("transaction %d is %s, expected %s", i, tx.Hash, expected)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+
+	for i := 1; i <= 30; i++ {
+		node.mine(i, "a", transfer(fmt.Sprintf("0xt%d", i), alice, bob))
+	}
+
+	scanner := NewScanner(context.Background(), db, client, 25)
+	db.AddSubscriber(context.Background(), models.HexToAddress(bob))
+	for n, err := scanner.Run(context.Background()); n != 0; n, err = scanner.Run(context.Background()) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+
+	if err := scanner.Backfill(models.HexToAddress(bob), 3); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	var status BackfillStatus
+	for {
+		status = scanner.Backfills()[0]
+		if status.Done || status.Err != nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !status.Done || status.Err != nil || status.To != 30 || status.Found != 28 {
+		t.Fatalf("unexpected backfill status %+v", status)
+	}
+
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(bob))
+	if len(txs) != 28 {
+		t.Fatalf("expected 28 transactions without duplicates, got %d", len(txs))
+	}
+	for i, tx := range txs {
+		if expected := fmt.Sprintf("0xt%d", i+3); tx.Hash != expected {
+			t.Fatalf("transaction %d is %s, expected %s", i, tx.Hash, expected)
+		}
+	}
+}
+
+func TestScannerAppliesReceipts(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xok", alice, bob), transfer("0xfail", alice, bob))
+	node.mu.Lock()
+	failed := node.receipts["0xfail"]
+	failed.Status = "0x0"
+	node.receipts["0xfail"] = failed
+	node.mu.Unlock()
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice))
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+	if txs[0].Status != models.TxStatusSuccess || txs[1].Status != models.TxStatusFailed {
+		t.Errorf("statuses = %v, %v; expected success, failed", txs[0].Status, txs[1].Status)
+	}
+	// 21000 gas at 1 gwei.
+	if fee := txs[0].Fee(); fee == nil || fee.String() != "21000000000000" {
+		t.Errorf("fee = %v, expected 21000000000000", fee)
+	}
+	// The reverted transfer only cost alice the fee.
+	if txs[0].Direction != models.DirectionOut || txs[0].Counterparty != models.HexToAddress(bob) ||
+		txs[0].BalanceDelta.String() != "-21000000000001" || txs[1].BalanceDelta.String() != "-21000000000000" {
+		t.Errorf("unexpected perspective %v %v %v, %v", txs[0].Direction, txs[0].Counterparty, txs[0].BalanceDelta, txs[1].BalanceDelta)
+	}
+}
+
+// failingDb fails SaveTransfers while fail is set.
+type failingDb struct {
+	*repo.MemoryDb
+	fail bool
+}
+
+func (f *failingDb) SaveTransfers(ctx context.Context, transfers map[models.Address][]models.TokenTransfer) error {
+	if f.fail {
+		return errors.New("disk full")
+	}
+	return f.MemoryDb.SaveTransfers(ctx, transfers)
+}
+
+func TestScannerKeepsCursorWhenStoringFails(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := &failingDb{MemoryDb: repo.NewDB(), fail: true}
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xt2", alice, bob))
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	if _, err := scanner.Run(context.Background()); err == nil {
+		t.Fatal("Run should fail when the block cannot be stored")
+	}
+	if scanner.GetCurrentBlock() != 1 {
+		t.Errorf("cursor moved to %d after a failed store", scanner.GetCurrentBlock())
+	}
+	if txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice)); len(txs) != 0 {
+		t.Errorf("partially stored block kept: %+v", txs)
+	}
+
+	db.fail = false
+	if n, err := scanner.Run(context.Background()); err != nil || n != 2 {
+		t.Fatalf("retry = %d, %v", n, err)
+	}
+	if txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice)); len(txs) != 1 {
+		t.Errorf("expected 1 transaction after the retry, got %d", len(txs))
+	}
+}
+
+func TestScannerSelfTransfer(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xself", alice, alice))
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice))
+	if len(txs) != 1 {
+		t.Fatalf("expected 1 record for a self-transfer, got %d", len(txs))
+	}
+	// The value comes back, so only the fee of 21000 gas at 1 gwei is paid.
+	if txs[0].Direction != models.DirectionSelf || txs[0].BalanceDelta.String() != "-21000000000000" {
+		t.Errorf("unexpected self-transfer %v %v", txs[0].Direction, txs[0].BalanceDelta)
+	}
+}
+
+func TestScannerTokenTransfers(t *testing.T) {
+	const token = "0x00000000000000000000000000000000000070c3"
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	// The token contract is called by bob, so only the log mentions alice.
+	node.mine(2, "a", transfer("0xcall", bob, token))
+	node.emit(2, "0xcall", token, bob, alice, 500)
+	node.emit(2, "0xcall", token, bob, bob, 7)
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice)); len(txs) != 0 {
+		t.Errorf("expected no transactions for alice, got %d", len(txs))
+	}
+	transfers, _ := db.GetTransfers(context.Background(), models.HexToAddress(alice))
+	if len(transfers) != 1 {
+		t.Fatalf("expected 1 token transfer, got %d", len(transfers))
+	}
+	got := transfers[0]
+	if got.Token != models.HexToAddress(token) || got.From != models.HexToAddress(bob) || got.To != models.HexToAddress(alice) || got.Amount.Int64() != 500 || got.LogIndex != 0 {
+		t.Errorf("unexpected transfer %+v", got)
+	}
+}
+
+func TestScannerSkipsLogsWithoutSubscribers(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xt2", alice, bob))
+	scanner := NewScanner(context.Background(), db, client, 1)
+	for n, err := scanner.Run(context.Background()); n != 0; n, err = scanner.Run(context.Background()) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	node.mu.Lock()
+	calls := node.calls["eth_getLogs"]
+	node.mu.Unlock()
+	if calls != 0 {
+		t.Errorf("expected no log queries without subscribers, got %d", calls)
+	}
+
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+	node.mine(3, "a", transfer("0xt3", alice, bob))
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	node.mu.Lock()
+	calls = node.calls["eth_getLogs"]
+	node.mu.Unlock()
+	if calls != 1 {
+		t.Errorf("expected 1 log query once subscribed, got %d", calls)
+	}
+}
+
+func TestScannerNFTTransfers(t *testing.T) {
+	const (
+		punks = "0x000000000000000000000000000000000000c0de"
+		items = "0x0000000000000000000000000000000000001155"
+	)
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xmint", bob, punks))
+	node.emitLog(2, "0xmint", punks, "0x",
+		TransferTopic, addressTopic(models.HexToAddress(bob)), addressTopic(models.HexToAddress(alice)), fmt.Sprintf("0x%064x", 42))
+	node.mine(3, "a", transfer("0xbatch", bob, items))
+	// ids [7, 8] with amounts [10, 20].
+	words := []int{0x40, 0xa0, 2, 7, 8, 2, 10, 20}
+	data := "0x"
+	for _, w := range words {
+		data += fmt.Sprintf("%064x", w)
+	}
+	node.emitLog(3, "0xbatch", items, data,
+		TransferBatchTopic, addressTopic(models.HexToAddress(bob)), addressTopic(models.HexToAddress(bob)), addressTopic(models.HexToAddress(alice)))
+	node.mine(4, "a")
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	for n, err := scanner.Run(context.Background()); n != 0; n, err = scanner.Run(context.Background()) {
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+
+	nfts, _ := db.GetNFTTransfers(context.Background(), models.HexToAddress(alice))
+	var got []string
+	for _, n := range nfts {
+		got = append(got, fmt.Sprintf("%s:%s:%v:%v", n.Standard, n.Contract, n.TokenID, n.Amount))
+	}
+	expected := fmt.Sprintf("erc721:%s:42:1,erc1155:%s:7:10,erc1155:%s:8:20",
+		models.HexToAddress(punks), models.HexToAddress(items), models.HexToAddress(items))
+	if strings.Join(got, ",") != expected {
+		t.Errorf("NFT transfers = %v, expected %s", got, expected)
+	}
+	if tokens, _ := db.GetTransfers(context.Background(), models.HexToAddress(alice)); len(tokens) != 0 {
+		t.Errorf("ERC-721 transfer stored as ERC-20 transfer: %+v", tokens)
+	}
+
+	received, _ := db.NFTsReceived(context.Background(), models.HexToAddress(alice), 3, 4)
+	if len(received) != 2 || received[0].Operator != models.HexToAddress(bob) {
+		t.Errorf("NFTs received in blocks 3..4 = %+v, expected the batch", received)
+	}
+}
+
+func TestScannerInternalTransfers(t *testing.T) {
+	const multisig = "0x000000000000000000000000000000000000515e"
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	node.mine(2, "a", transfer("0xexec", bob, multisig))
+	node.traces[2] = []ethclient.TxTrace{{
+		TxHash: "0xexec",
+		Result: ethclient.CallFrame{
+			Type: "CALL", From: bob, To: multisig, Value: "0x0",
+			Calls: []ethclient.CallFrame{
+				{Type: "STATICCALL", From: multisig, To: bob},
+				{Type: "CALL", From: multisig, To: alice, Value: "0xde0b6b3a7640000"},
+			},
+		},
+	}}
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	scanner.Traces = true
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	internal, _ := db.GetInternalTransfers(context.Background(), models.HexToAddress(alice))
+	if len(internal) != 1 {
+		t.Fatalf("expected 1 internal transfer, got %d", len(internal))
+	}
+	got := internal[0]
+	if got.TxHash != "0xexec" || got.From != models.HexToAddress(multisig) || got.To != models.HexToAddress(alice) ||
+		got.Value.String() != "1000000000000000000" || got.TraceAddress != "1" {
+		t.Errorf("unexpected internal transfer %+v", got)
+	}
+}
+
+func TestScannerDeployments(t *testing.T) {
+	const child = "0x000000000000000000000000000000000000c41d"
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	deploy := ethclient.Transaction{Hash: "0xdeploy", From: alice, Nonce: "0x5", Value: "0x0"}
+	node.mine(2, "a", deploy, transfer("0xspawn", bob, alice))
+	node.traces[2] = []ethclient.TxTrace{
+		{TxHash: "0xdeploy", Result: ethclient.CallFrame{Type: "CREATE", From: alice}},
+		{TxHash: "0xspawn", Result: ethclient.CallFrame{
+			Type: "CALL", From: bob, To: alice, Value: "0x0",
+			Calls: []ethclient.CallFrame{{Type: "CREATE2", From: alice, To: child, Value: "0x0"}},
+		}},
+	}
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	scanner.Traces = true
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	hex, _ := crypto.CreateAddress(alice, 5)
+	created := models.HexToAddress(hex)
+	txs, _ := db.GetTxns(context.Background(), models.HexToAddress(alice))
+	if len(txs) != 2 || txs[0].Kind != models.TxKindDeployment || txs[0].ContractAddress != created {
+		t.Fatalf("expected deployment of %s first, got %+v", created, txs)
+	}
+
+	var events []string
+	for len(scanner.Deployments()) > 0 {
+		event := <-scanner.Deployments()
+		events = append(events, fmt.Sprintf("%s:%v", event.Contract, event.Factory))
+	}
+	expected := fmt.Sprintf("%s:false,%s:true", created, models.HexToAddress(child))
+	if strings.Join(events, ",") != expected {
+		t.Errorf("deployment events = %v, expected %s", events, expected)
+	}
+}
+
+func TestParseTypedTransactions(t *testing.T) {
+	const raw = `[
+		{"type":"0x0","gasPrice":"0x64","nonce":"0x0"},
+		{"type":"0x2","gasPrice":"0x5a","maxFeePerGas":"0x96","maxPriorityFeePerGas":"0xa","accessList":[{"address":"0x0000000000000000000000000000000000000b0b","storageKeys":["0x2"]}]},
+		{"type":"0x2","maxFeePerGas":"0x55","maxPriorityFeePerGas":"0xa"},
+		{"type":"0x3","maxFeePerGas":"0x96","maxPriorityFeePerGas":"0x1","maxFeePerBlobGas":"0x7","blobVersionedHashes":["0x01ab"]},
+		{"type":"0x4","maxFeePerGas":"0x96","maxPriorityFeePerGas":"0x0","authorizationList":[{"chainId":"0x1","address":"0x00000000000000000000000000000000000a11ce","nonce":"0x3","yParity":"0x1","r":"0x1","s":"0x2"}]}
+	]`
+	block := &ethclient.Block{BaseFeePerGas: "0x50"}
+	if err := json.Unmarshal([]byte(raw), &block.Transactions); err != nil {
+		t.Fatalf("decoding transactions: %v", err)
+	}
+	txs := parseTxs(block)
+
+	expected := []struct {
+		typ   models.TxType
+		price int64
+	}{
+		{models.LegacyTxType, 100},
+		{models.DynamicFeeTxType, 90}, // base fee 80 + tip 10
+		{models.DynamicFeeTxType, 85}, // capped by maxFeePerGas
+		{models.BlobTxType, 81},
+		{models.SetCodeTxType, 80},
+	}
+	for i, e := range expected {
+		if txs[i].Type != e.typ || txs[i].EffectiveGasPrice == nil || txs[i].EffectiveGasPrice.Int64() != e.price {
+			t.Errorf("tx %d: type %s, effective gas price %v; expected %s, %d",
+				i, txs[i].Type, txs[i].EffectiveGasPrice, e.typ, e.price)
+		}
+	}
+	if len(txs[1].AccessList) != 1 || txs[1].AccessList[0].Address != models.HexToAddress(bob) || txs[1].AccessList[0].StorageKeys[0] != "0x2" {
+		t.Errorf("access list not decoded: %+v", txs[1].AccessList)
+	}
+	if txs[3].MaxFeePerBlobGas.Int64() != 7 || len(txs[3].BlobVersionedHashes) != 1 {
+		t.Errorf("blob fields not decoded: %+v", txs[3])
+	}
+	if auths := txs[4].AuthorizationList; len(auths) != 1 || auths[0].Address != models.HexToAddress(alice) || auths[0].ChainID.Int64() != 1 ||
+		auths[0].Nonce != 3 || auths[0].YParity != 1 || auths[0].S.Int64() != 2 {
+		t.Errorf("authorization list not decoded: %+v", txs[4].AuthorizationList)
+	}
+}
+
+func TestParseBlock(t *testing.T) {
+	const raw = `{
+		"number":"0x10","hash":"0xh","parentHash":"0xp","timestamp":"0x6553f100",
+		"miner":"0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97","gasUsed":"0x5208","gasLimit":"0x1c9c380","baseFeePerGas":"0x7",
+		"blobGasUsed":"0x20000","excessBlobGas":"0x0",
+		"withdrawals":[{"index":"0x1","validatorIndex":"0x2","address":"0xa","amount":"0x3b9aca00"}],
+		"transactions":[{"hash":"0xt","type":"0x0","gasPrice":"0x9"}]
+	}`
+	var block ethclient.Block
+	if err := json.Unmarshal([]byte(raw), &block); err != nil {
+		t.Fatalf("decoding block: %v", err)
+	}
+	parsed := ParseBlock(&block)
+
+	when := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	if !parsed.Timestamp.Equal(when) || parsed.FeeRecipient.Hex() != "0x4838B106FCe9647Bdf1E7877BF73cE8B0BAD5f97" || parsed.GasLimit.Int64() != 30000000 ||
+		parsed.BaseFeePerGas.Int64() != 7 || parsed.BlobGasUsed.Int64() != 131072 || parsed.ExcessBlobGas.Sign() != 0 {
+		t.Errorf("unexpected block %+v", parsed)
+	}
+	if len(parsed.Withdrawals) != 1 || parsed.Withdrawals[0].ValidatorIndex != 2 || parsed.Withdrawals[0].Amount.Int64() != 1e9 {
+		t.Errorf("unexpected withdrawals %+v", parsed.Withdrawals)
+	}
+	if len(parsed.Transactions) != 1 || !parsed.Transactions[0].Timestamp.Equal(when) {
+		t.Errorf("transactions not stamped with the block time: %+v", parsed.Transactions)
+	}
+}
+
+func TestScannerWithdrawals(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	block := node.mine(2, "a")
+	block.Withdrawals = []ethclient.Withdrawal{
+		{Index: "0x10", ValidatorIndex: "0x7", Address: "0x00000000000000000000000000000000000A11CE", Amount: "0x1bc16d6"},
+		{Index: "0x11", ValidatorIndex: "0x8", Address: bob, Amount: "0x1"},
+	}
+	node.mu.Lock()
+	node.blocks[2] = block
+	node.mu.Unlock()
+
+	scanner := NewScanner(context.Background(), db, client, 2)
+	if _, err := scanner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	withdrawals, _ := db.GetWithdrawals(context.Background(), models.HexToAddress(alice))
+	if len(withdrawals) != 1 {
+		t.Fatalf("expected 1 withdrawal, got %d", len(withdrawals))
+	}
+	// 29,103,830 gwei.
+	if w := withdrawals[0]; w.Index != 16 || w.ValidatorIndex != 7 || w.Amount.String() != "29103830000000000" {
+		t.Errorf("unexpected withdrawal %+v", w)
+	}
+}
+
+func TestScannerSettlesPendingTransactions(t *testing.T) {
+	node, client := newFakeNode(t)
+	db := repo.NewDB()
+	db.AddSubscriber(context.Background(), models.HexToAddress(alice))
+
+	node.mine(1, "a")
+	sent := ethclient.Transaction{Hash: "0xsent", From: alice, To: bob, Nonce: "0x1", Value: "0x1"}
+	incoming := ethclient.Transaction{Hash: "0xincoming", From: bob, To: alice, Nonce: "0x3", Value: "0x1"}
+	node.mu.Lock()
+	node.pool = []ethclient.Transaction{sent, incoming}
+	node.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scanner := NewScanner(ctx, db, client, 2)
+	scanner.PollTxPool(5 * time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if pending, _ := db.GetPending(ctx, models.HexToAddress(alice)); len(pending) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pending transactions were not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Bob speeds up his transfer with a new transaction for the same nonce.
+	node.mine(2, "a", sent, ethclient.Transaction{Hash: "0xspeedup", From: bob, To: alice, Nonce: "0x3", Value: "0x1"})
+	if _, err := scanner.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	pending, _ := db.GetPending(ctx, models.HexToAddress(alice))
+	states := make(map[string]string)
+	for _, p := range pending {
+		states[p.Hash] = p.State.String() + p.ReplacedBy
+	}
+	if states["0xsent"] != "mined" || states["0xincoming"] != "replaced0xspeedup" {
+		t.Errorf("unexpected pending states %v", states)
+	}
+	if txs, _ := db.GetTxns(ctx, models.HexToAddress(alice)); len(txs) != 2 {
+		t.Errorf("expected both mined transactions to be stored, got %d", len(txs))
+	}
+
+	node.mu.Lock()
+	node.pool = nil
+	node.mu.Unlock()
+	// Announced only after its block was committed.
+	scanner.recordPending(ctx, &ethclient.Transaction{Hash: "0xspeedup", From: bob, To: alice, Nonce: "0x3", Value: "0x1"})
+	pending, _ = db.GetPending(ctx, models.HexToAddress(alice))
+	if late := pending[len(pending)-1]; late.Hash != "0xspeedup" || late.State != models.Mined {
+		t.Errorf("late announcement not settled: %s %v", late.Hash, late.State)
+	}
+
+	scanner.recordPending(ctx, &ethclient.Transaction{Hash: "0xstuck", From: alice, To: bob, Nonce: "0x2"})
+	if n, _ := db.ExpirePending(ctx, time.Now().Add(time.Second)); n != 1 {
+		t.Errorf("expected the stuck transaction to be dropped, %d were", n)
+	}
+}
@@ -0,0 +1,124 @@
+package scannersvc
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/trust-assignment/internal/models"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// Event signature hashes of the token transfer events the scanner decodes.
+const (
+	// TransferTopic is Transfer(address,address,uint256), shared by ERC-20
+	// and ERC-721.
+	TransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	// TransferSingleTopic is the ERC-1155
+	// TransferSingle(address,address,address,uint256,uint256).
+	TransferSingleTopic = "0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62"
+	// TransferBatchTopic is the ERC-1155
+	// TransferBatch(address,address,address,uint256[],uint256[]).
+	TransferBatchTopic = "0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb"
+)
+
+var transferTopics = []string{TransferTopic, TransferSingleTopic, TransferBatchTopic}
+
+// transferLogs fetches the token transfer logs emitted in the block with the
+// given hash. Querying by hash rather than number guarantees the logs belong
+// to the block that is about to be committed.
+func (s *ScannerService) transferLogs(ctx context.Context, blockHash string) ([]ethclient.Log, error) {
+	return s.Client.FilterLogs(ctx, ethclient.FilterQuery{
+		BlockHash: blockHash,
+		Topics:    [][]string{transferTopics},
+	})
+}
+
+// parseTransfer decodes an ERC-20 Transfer log. ERC-721 uses the same event
+// signature but indexes the token id as a fourth topic; such logs are
+// rejected.
+func parseTransfer(l ethclient.Log) (models.TokenTransfer, bool) {
+	if len(l.Topics) != 3 || !strings.EqualFold(l.Topics[0], TransferTopic) || l.Removed {
+		return models.TokenTransfer{}, false
+	}
+	return models.TokenTransfer{
+		Token:       models.HexToAddress(l.Address),
+		From:        topicAddress(l.Topics[1]),
+		To:          topicAddress(l.Topics[2]),
+		Amount:      decodeHexString(l.Data),
+		TxHash:      l.TransactionHash,
+		BlockNumber: decodeHexString(l.BlockNumber),
+		BlockHash:   l.BlockHash,
+		LogIndex:    decodeHexString(l.LogIndex).Uint64(),
+	}, true
+}
+
+// topicAddress extracts the address from a 32-byte indexed topic.
+func topicAddress(topic string) models.Address {
+	topic = strings.TrimPrefix(topic, "0x")
+	if len(topic) > 40 {
+		topic = topic[len(topic)-40:]
+	}
+	return models.HexToAddress(topic)
+}
+
+// addressTopic left-pads address to a 32-byte topic, for log filters.
+func addressTopic(address models.Address) string {
+	return "0x" + strings.Repeat("0", 24) + hex.EncodeToString(address[:])
+}
+
+// PullTransfers decodes the ERC-20 transfers in logs and groups the ones
+// sent from or to a subscribed address by that address.
+func (s *ScannerService) PullTransfers(ctx context.Context, logs []ethclient.Log) map[models.Address][]models.TokenTransfer {
+	result := make(map[models.Address][]models.TokenTransfer)
+	for _, l := range logs {
+		t, ok := parseTransfer(l)
+		if !ok {
+			continue
+		}
+		if ok, _ := s.Db.CheckTxns(ctx, t.From); ok {
+			result[t.From] = append(result[t.From], t)
+		}
+		if t.To != t.From {
+			if ok, _ := s.Db.CheckTxns(ctx, t.To); ok {
+				result[t.To] = append(result[t.To], t)
+			}
+		}
+	}
+	return result
+}
+
+// addressLogs returns the token transfer logs between blocks from and to
+// that mention address as sender or recipient, filtering on the indexed
+// topics so the node only returns matching logs. ERC-20 and ERC-721 index
+// the sender and recipient as topics 1 and 2, ERC-1155 as topics 2 and 3
+// after the operator; matches on the operator are returned too and must be
+// filtered out by the caller.
+func (s *ScannerService) addressLogs(ctx context.Context, address models.Address, from, to int) ([]ethclient.Log, error) {
+	topic := []string{addressTopic(address)}
+	queries := [][][]string{
+		{transferTopics, topic},
+		{transferTopics, nil, topic},
+		{{TransferSingleTopic, TransferBatchTopic}, nil, nil, topic},
+	}
+	type key struct {
+		hash  string
+		index string
+	}
+	seen := make(map[key]bool)
+	var logs []ethclient.Log
+	for _, topics := range queries {
+		found, err := s.Client.FilterLogs(ctx, ethclient.FilterQuery{FromBlock: from, ToBlock: to, Topics: topics})
+		if err != nil {
+			return nil, err
+		}
+		// A log can match several queries, e.g. a transfer to oneself.
+		for _, l := range found {
+			if k := (key{l.TransactionHash, l.LogIndex}); !seen[k] {
+				seen[k] = true
+				logs = append(logs, l)
+			}
+		}
+	}
+	return logs, nil
+}
@@ -0,0 +1,57 @@
+package scannersvc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/trust-assignment/internal/models"
+	"github.com/trust-assignment/pkg/ethclient"
+)
+
+// internalCalls traces block for value transfers made by contracts when
+// Traces is enabled. A node that supports no tracing method yields none.
+func (s *ScannerService) internalCalls(ctx context.Context, block *ethclient.Block) ([]ethclient.InternalCall, error) {
+	if !s.Traces {
+		return nil, nil
+	}
+	hashes := make([]string, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.Hash
+	}
+	calls, err := s.Client.InternalCalls(ctx, int(decodeHexString(block.Number).Int64()), hashes)
+	if errors.Is(err, ethclient.ErrTracingUnsupported) {
+		return nil, nil
+	}
+	return calls, err
+}
+
+func parseInternalTransfer(call ethclient.InternalCall, block *ethclient.Block) models.InternalTransfer {
+	return models.InternalTransfer{
+		TxHash:       call.TxHash,
+		BlockNumber:  decodeHexString(block.Number),
+		BlockHash:    block.Hash,
+		Type:         call.Type,
+		From:         models.HexToAddress(call.From),
+		To:           models.HexToAddress(call.To),
+		Value:        decodeHexString(call.Value),
+		TraceAddress: call.TraceAddress,
+	}
+}
+
+// PullInternalTransfers groups the internal transfers of block sent from or
+// to a subscribed address by that address.
+func (s *ScannerService) PullInternalTransfers(ctx context.Context, block *ethclient.Block, calls []ethclient.InternalCall) map[models.Address][]models.InternalTransfer {
+	result := make(map[models.Address][]models.InternalTransfer)
+	for _, call := range calls {
+		t := parseInternalTransfer(call, block)
+		if ok, _ := s.Db.CheckTxns(ctx, t.From); ok {
+			result[t.From] = append(result[t.From], t)
+		}
+		if t.To != t.From {
+			if ok, _ := s.Db.CheckTxns(ctx, t.To); ok {
+				result[t.To] = append(result[t.To], t)
+			}
+		}
+	}
+	return result
+}
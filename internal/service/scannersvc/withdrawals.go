@@ -0,0 +1,41 @@
+package scannersvc
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/trust-assignment/internal/models"
+)
+
+// weiPerGwei converts withdrawal amounts, which the node reports in gwei.
+var weiPerGwei = big.NewInt(1e9)
+
+// parseWithdrawals converts the withdrawals of block into records with the
+// amount in wei.
+func parseWithdrawals(block models.Block) []models.WithdrawalTransfer {
+	withdrawals := make([]models.WithdrawalTransfer, len(block.Withdrawals))
+	for i, w := range block.Withdrawals {
+		withdrawals[i] = models.WithdrawalTransfer{
+			Index:          w.Index,
+			ValidatorIndex: w.ValidatorIndex,
+			Address:        w.Address,
+			Amount:         new(big.Int).Mul(w.Amount, weiPerGwei),
+			BlockNumber:    new(big.Int).Set(block.Number),
+			BlockHash:      block.Hash,
+			Timestamp:      block.Timestamp,
+		}
+	}
+	return withdrawals
+}
+
+// PullWithdrawals groups the withdrawals of block credited to a subscribed
+// address by that address.
+func (s *ScannerService) PullWithdrawals(ctx context.Context, block models.Block) map[models.Address][]models.WithdrawalTransfer {
+	result := make(map[models.Address][]models.WithdrawalTransfer)
+	for _, w := range parseWithdrawals(block) {
+		if ok, _ := s.Db.CheckTxns(ctx, w.Address); ok {
+			result[w.Address] = append(result[w.Address], w)
+		}
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// CreateAddress returns the address of the contract deployed by sender in a
+// transaction with the given nonce: the last 20 bytes of
+// keccak256(rlp([sender, nonce])). sender must be a 0x-prefixed hex address.
+func CreateAddress(sender string, nonce uint64) (string, error) {
+	from, err := hex.DecodeString(strings.TrimPrefix(sender, "0x"))
+	if err != nil {
+		return "", err
+	}
+	payload := append(rlpBytes(from), rlpUint(nonce)...)
+	hash := Keccak256(rlpListHeader(len(payload)), payload)
+	return "0x" + hex.EncodeToString(hash[12:]), nil
+}
+
+// rlpBytes RLP-encodes a byte string shorter than 56 bytes.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return b
+	}
+	return append([]byte{0x80 + byte(len(b))}, b...)
+}
+
+// rlpUint RLP-encodes an integer as its minimal big-endian byte string.
+func rlpUint(n uint64) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return rlpBytes(b)
+}
+
+// rlpListHeader returns the RLP prefix of a list whose encoded items take
+// size bytes, which must be less than 56.
+func rlpListHeader(size int) []byte {
+	return []byte{0xc0 + byte(size)}
+}
@@ -0,0 +1,39 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"Transfer(address,address,uint256)", "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+	}
+	for _, test := range tests {
+		if got := hex.EncodeToString(Keccak256([]byte(test.input))); got != test.expected {
+			t.Errorf("Keccak256(%q) = %s, expected %s", test.input, got, test.expected)
+		}
+	}
+}
+
+func TestCreateAddress(t *testing.T) {
+	tests := []struct {
+		sender   string
+		nonce    uint64
+		expected string
+	}{
+		{"0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0", 0, "0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d"},
+		{"0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0", 1, "0x343c43a37d37dff08ae8c4a11544c718abb4fcf8"},
+		{"0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0", 2, "0xf778b86fa74e846c4f0a1fbd1335fe81c00a0c91"},
+	}
+	for _, test := range tests {
+		got, err := CreateAddress(test.sender, test.nonce)
+		if err != nil || got != test.expected {
+			t.Errorf("CreateAddress(%s, %d) = %s, %v; expected %s", test.sender, test.nonce, got, err, test.expected)
+		}
+	}
+}
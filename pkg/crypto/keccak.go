@@ -0,0 +1,88 @@
+// Package crypto implements the Ethereum hashing and address derivation the
+// parser needs, without third-party dependencies.
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// rate is the Keccak-256 block size in bytes: (1600 - 2*256) / 8.
+const rate = 136
+
+var roundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var rotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Keccak256 returns the Keccak-256 hash of the concatenated data, as used by
+// Ethereum. It differs from the standardised SHA3-256 in its padding.
+func Keccak256(data ...[]byte) []byte {
+	var msg []byte
+	for _, d := range data {
+		msg = append(msg, d...)
+	}
+	// Pad with the original Keccak multi-rate padding 0x01 ... 0x80.
+	padded := make([]byte, len(msg)+rate-len(msg)%rate)
+	copy(padded, msg)
+	padded[len(msg)] |= 0x01
+	padded[len(padded)-1] |= 0x80
+
+	var state [25]uint64
+	for block := padded; len(block) > 0; block = block[rate:] {
+		for i := 0; i < rate/8; i++ {
+			state[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+		}
+		keccakF(&state)
+	}
+
+	out := make([]byte, 32)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], state[i])
+	}
+	return out
+}
+
+// keccakF applies the Keccak-f[1600] permutation. Lanes are indexed x+5y.
+func keccakF(a *[25]uint64) {
+	var c [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		// θ
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[x+y] ^= d
+			}
+		}
+		// ρ and π
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], rotations[x+5*y])
+			}
+		}
+		// χ
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[x+y] = b[x+y] ^ (^b[(x+1)%5+y] & b[(x+2)%5+y])
+			}
+		}
+		// ι
+		a[0] ^= roundConstants[round]
+	}
+}
@@ -0,0 +1,101 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BatchElem is a single call in a JSON-RPC batch. Result must be a pointer
+// the call's result is decoded into; Error is set when that individual call
+// fails, independently of the other calls in the batch.
+type BatchElem struct {
+	Method string
+	Params interface{}
+	Result interface{}
+	Error  error
+}
+
+// Batch sends all elems as one JSON-RPC batch request and matches the
+// responses back to their calls by id. The returned error is only non-nil
+// when the batch as a whole could not be sent or decoded; per-call failures
+// are reported in each element's Error field. The batch is retried as a
+// whole according to the client's retry policy.
+func (ec *EthClient) Batch(ctx context.Context, elems []BatchElem) error {
+	if len(elems) == 0 {
+		return nil
+	}
+	return ec.withRetry(ctx, "batch", func(url string) error {
+		return ec.batchOnce(ctx, url, elems)
+	})
+}
+
+func (ec *EthClient) batchOnce(ctx context.Context, url string, elems []BatchElem) error {
+	requests := make([]RequestBody, len(elems))
+	byID := make(map[uint64]int, len(elems))
+	for i, elem := range elems {
+		requests[i] = ec.createRequest(elem.Method, elem.Params)
+		byID[requests[i].ID] = i
+	}
+
+	requestBody, err := json.Marshal(requests)
+	if err != nil {
+		return fmt.Errorf("[eth-client] Error in JSON Marshal: %v", err)
+	}
+
+	responseBody, err := ec.post(ctx, url, "batch", requestBody)
+	if err != nil {
+		return err
+	}
+
+	var responses []JSONRPCResponse
+	if err := json.Unmarshal(responseBody, &responses); err != nil {
+		// Nodes that reject the batch as a whole answer with a single
+		// error object instead of an array.
+		var single JSONRPCResponse
+		if json.Unmarshal(responseBody, &single) == nil && single.Error != nil {
+			return single.Error
+		}
+		return fmt.Errorf("[eth-client] Error decoding batch Response Body: %v", err)
+	}
+
+	answered := make([]bool, len(elems))
+	for _, resp := range responses {
+		i, ok := byID[resp.ID]
+		if !ok || answered[i] {
+			continue
+		}
+		answered[i] = true
+		elems[i].Error = decodeResult(elems[i].Method, &resp, elems[i].Result)
+	}
+	for i := range elems {
+		if !answered[i] {
+			elems[i].Error = fmt.Errorf("[eth-client] missing response for %s in batch", elems[i].Method)
+		}
+	}
+	return nil
+}
+
+// BlocksByNumber fetches several blocks, with full transactions, in a single
+// batch request. Blocks are returned in the same order as numbers.
+func (ec *EthClient) BlocksByNumber(ctx context.Context, numbers []int) ([]*Block, error) {
+	blocks := make([]*Block, len(numbers))
+	elems := make([]BatchElem, len(numbers))
+	for i, n := range numbers {
+		blocks[i] = new(Block)
+		elems[i] = BatchElem{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{fmt.Sprintf("0x%x", n), true},
+			Result: blocks[i],
+		}
+	}
+	if err := ec.Batch(ctx, elems); err != nil {
+		return nil, err
+	}
+	for i, elem := range elems {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("[eth-client] block %d: %w", numbers[i], elem.Error)
+		}
+	}
+	return blocks, nil
+}
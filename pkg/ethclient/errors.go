@@ -0,0 +1,107 @@
+package ethclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRateLimited is matched by errors caused by the endpoint throttling
+	// us, either with HTTP 429 or the JSON-RPC "limit exceeded" error code.
+	ErrRateLimited = errors.New("[eth-client] rate limited")
+	// ErrNotFound is returned when the node answers with a null result, for
+	// example when asking for a block that has not been mined yet.
+	ErrNotFound = errors.New("[eth-client] not found")
+	// ErrHTTPStatus is matched by every *HTTPError.
+	ErrHTTPStatus = errors.New("[eth-client] unexpected HTTP status")
+	// ErrMethodNotFound is matched by RPC errors reporting that the node
+	// does not implement the requested method.
+	ErrMethodNotFound = errors.New("[eth-client] method not supported")
+	// ErrTracingUnsupported is returned by InternalCalls when the node
+	// implements neither debug_traceBlockByNumber nor trace_block.
+	ErrTracingUnsupported = errors.New("[eth-client] node does not support block tracing")
+)
+
+const (
+	// CodeLimitExceeded is the EIP-1474 error code for "request exceeds
+	// defined limit", which providers use to signal rate limiting.
+	CodeLimitExceeded = -32005
+	// CodeMethodNotFound is the JSON-RPC 2.0 "method not found" error code.
+	CodeMethodNotFound = -32601
+)
+
+// CanceledError is returned when a request is abandoned because its context
+// was cancelled or its deadline expired. It unwraps to the context error, so
+// errors.Is(err, context.Canceled) and errors.Is(err, context.DeadlineExceeded)
+// work as expected.
+type CanceledError struct {
+	Method string
+	Err    error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("[eth-client] %s request canceled: %v", e.Method, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// RPCError is the error object of a JSON-RPC response.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("[eth-client] rpc error %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("[eth-client] rpc error %d: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.Code == CodeLimitExceeded
+	case ErrMethodNotFound:
+		// Not every provider uses the standard code for unsupported
+		// methods, so fall back to recognising the usual messages.
+		msg := strings.ToLower(e.Message)
+		return e.Code == CodeMethodNotFound ||
+			strings.Contains(msg, "not supported") ||
+			strings.Contains(msg, "does not exist") ||
+			strings.Contains(msg, "not available")
+	}
+	return false
+}
+
+// HTTPError is returned when the endpoint answers with a non-200 status.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string        // first bytes of the response body, for diagnostics
+	RetryAfter time.Duration // wait requested by a Retry-After header, if any
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("[eth-client] HTTP %s: %s", e.Status, e.Body)
+	}
+	return fmt.Sprintf("[eth-client] HTTP %s", e.Status)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrHTTPStatus:
+		return true
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
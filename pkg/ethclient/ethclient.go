@@ -2,50 +2,89 @@ package ethclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"math/rand"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// DefaultTimeout bounds a single JSON-RPC round-trip when the caller
+	// does not supply its own http.Client.
+	DefaultTimeout = 30 * time.Second
+)
+
 type EthClient struct {
-	endpoint string
+	pool  *endpointPool
+	wsURL string
+	// noBlockReceipts is set once the node has rejected eth_getBlockReceipts.
+	noBlockReceipts atomic.Bool
+	// tracer is the tracing method the node supports, see InternalCalls.
+	tracer     atomic.Int32
+	httpClient *http.Client
+	retry      RetryPolicy
+	lastID     atomic.Uint64 // last JSON-RPC request id handed out
 }
 
-func NewEthClient(URL string) *EthClient {
-	return &EthClient{
-		endpoint: URL,
+// Option configures an EthClient.
+type Option func(*EthClient)
+
+// WithHTTPClient sets the http.Client used for every request. The client's
+// timeouts apply on top of the deadline carried by the request context.
+func WithHTTPClient(client *http.Client) Option {
+	return func(ec *EthClient) {
+		ec.httpClient = client
 	}
 }
 
-// BlockNumber returns the current block number. It will call
-// the eth_blockNumber method of the JSON-RPC API in the given url.
-
-func (ec *EthClient) BlockNumber() (int, error) {
+// NewEthClient returns a client for the JSON-RPC endpoint at URL. Further
+// endpoints can be added with WithEndpoints.
+func NewEthClient(URL string, opts ...Option) *EthClient {
+	ec := &EthClient{
+		pool:       newEndpointPool(URL),
+		httpClient: NewHTTPClient(DefaultTimeout),
+		retry:      DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(ec)
+	}
+	return ec
+}
 
-	requestBody, err := json.Marshal(createRequest("eth_blockNumber", []string{}))
-	if err != nil {
-		return 0, err
+// NewHTTPClient returns an http.Client with the given overall request
+// timeout and conservative dial and TLS handshake limits.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   10,
+		},
 	}
+}
 
-	resp, err := http.Post(ec.endpoint, "application/json", bytes.NewReader(requestBody))
-	if err != nil {
+// BlockNumber returns the current block number. It will call
+// the eth_blockNumber method of the JSON-RPC API in the given url.
+func (ec *EthClient) BlockNumber(ctx context.Context) (int, error) {
+	var result string
+	if err := ec.call(ctx, "eth_blockNumber", []string{}, &result); err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
-	var responseBody struct {
-		Jsonrpc string `json:"jsonrpc"`
-		ID      int    `json:"id"`
-		Result  string `json:"result"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
-		return 0, fmt.Errorf("[eth-client] Error decoding response body: %v", err)
-	}
 
-	blocknumber, err := strconv.ParseInt(responseBody.Result[2:], 16, 64)
+	blocknumber, err := parseQuantity(result)
 	if err != nil {
 		return 0, fmt.Errorf("[eth-client] Error parsing response body: %v", err)
 	}
@@ -53,40 +92,136 @@ func (ec *EthClient) BlockNumber() (int, error) {
 	return int(blocknumber), nil
 }
 
+// parseQuantity decodes a hex-encoded JSON-RPC quantity such as "0x1b4".
+func parseQuantity(s string) (int64, error) {
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok || digits == "" {
+		return 0, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return strconv.ParseInt(digits, 16, 64)
+}
+
 // BlockByNumber retrieves information about a specific block by its number.
-func (ec *EthClient) BlockByNumber(blockNumber int) (*Block, error) {
-	method := "eth_getBlockByNumber"
+// It returns an error matching ErrNotFound if the block does not exist yet.
+func (ec *EthClient) BlockByNumber(ctx context.Context, blockNumber int) (*Block, error) {
 	params := []interface{}{fmt.Sprintf("0x%x", blockNumber), true}
-	requestBody, err := json.Marshal(createRequest(method, params))
+	var block Block
+	if err := ec.call(ctx, "eth_getBlockByNumber", params, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Block tags accepted by HeaderByTag.
+const (
+	TagLatest    = "latest"
+	TagSafe      = "safe"
+	TagFinalized = "finalized"
+)
+
+// HeaderByTag returns the header of the block the node currently labels
+// with tag, without its transactions. Nodes that predate the merge do not
+// know the safe and finalized tags and answer with an RPC error.
+func (ec *EthClient) HeaderByTag(ctx context.Context, tag string) (*Header, error) {
+	var header Header
+	if err := ec.call(ctx, "eth_getBlockByNumber", []interface{}{tag, false}, &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// call performs a JSON-RPC request, retrying transient failures according
+// to the client's retry policy, and decodes its result into result.
+func (ec *EthClient) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	return ec.withRetry(ctx, method, func(url string) error {
+		return ec.callOnce(ctx, url, method, params, result)
+	})
+}
+
+func (ec *EthClient) callOnce(ctx context.Context, url, method string, params interface{}, result interface{}) error {
+	requestBody, err := json.Marshal(ec.createRequest(method, params))
 	if err != nil {
-		return nil, fmt.Errorf("[eth-client] Error in JSON Marshal: %v", err)
+		return fmt.Errorf("[eth-client] Error in JSON Marshal: %v", err)
 	}
 
-	resp, err := http.Post(ec.endpoint, "application/json", bytes.NewReader(requestBody))
+	responseBody, err := ec.post(ctx, url, method, requestBody)
+	if err != nil {
+		return err
+	}
+
+	var jsonResponse JSONRPCResponse
+	if err := json.Unmarshal(responseBody, &jsonResponse); err != nil {
+		return fmt.Errorf("[eth-client] Error decoding Response Body: %v", err)
+	}
+	return decodeResult(method, &jsonResponse, result)
+}
+
+// decodeResult checks a single JSON-RPC response for an error object or a
+// null result before decoding the result into result.
+func decodeResult(method string, resp *JSONRPCResponse, result interface{}) error {
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return fmt.Errorf("%w: %s returned null", ErrNotFound, method)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("[eth-client] Error decoding %s result: %v", method, err)
+	}
+	return nil
+}
+
+// post sends body to url and returns the raw response body. If the
+// request fails because ctx was cancelled or its deadline passed, the error
+// is a *CanceledError; a non-200 status yields an *HTTPError.
+func (ec *EthClient) post(ctx context.Context, url, method string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("[eth-client] Error in creating Request: %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ec.httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, &CanceledError{Method: method, Err: ctxErr}
+		}
+		return nil, fmt.Errorf("[eth-client] Error sending %s request: %w", method, err)
+	}
 	defer resp.Body.Close()
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("[eth-client] Error reading Response Body: %v", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, &CanceledError{Method: method, Err: ctxErr}
+		}
+		return nil, fmt.Errorf("[eth-client] Error reading Response Body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       truncate(string(responseBody), 256),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
+	return responseBody, nil
+}
 
-	var jsonResponse JSONRPCResponse
-	if err := json.Unmarshal(responseBody, &jsonResponse); err != nil {
-		return nil, fmt.Errorf("[eth-client] Error decoding Response Body: %v", err)
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
-	fmt.Println("Response--->", &jsonResponse)
-	return &jsonResponse.Result, nil
+	return s[:n] + "..."
 }
 
-// createRequest generates a JSON-RPC request.
-func createRequest(method string, params interface{}) RequestBody {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+// createRequest generates a JSON-RPC request. IDs are unique for the
+// lifetime of the client so responses to batch requests can be matched
+// back to their calls.
+func (ec *EthClient) createRequest(method string, params interface{}) RequestBody {
 	return RequestBody{
 		Jsonrpc: "2.0",
-		ID:      int(r.Uint64()),
+		ID:      ec.lastID.Add(1),
 		Method:  method,
 		Params:  params,
 	}
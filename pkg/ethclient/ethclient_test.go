@@ -0,0 +1,150 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBlockNumber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer srv.Close()
+
+	client := NewEthClient(srv.URL)
+	number, err := client.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("BlockNumber failed: %v", err)
+	}
+	if number != 16 {
+		t.Errorf("BlockNumber returned %d, expected 16", number)
+	}
+}
+
+func TestCallCanceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewEthClient(srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.BlockNumber(ctx)
+	var canceled *CanceledError
+	if !errors.As(err, &canceled) {
+		t.Fatalf("expected *CanceledError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestBatchMatchesResponsesByID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []RequestBody
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			t.Errorf("decoding batch request: %v", err)
+			return
+		}
+		// Answer in reverse order and leave the last call unanswered.
+		var resps []map[string]interface{}
+		for i := len(reqs) - 2; i >= 0; i-- {
+			number := reqs[i].Params.([]interface{})[0]
+			resps = append(resps, map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      reqs[i].ID,
+				"result":  map[string]interface{}{"number": number},
+			})
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+	defer srv.Close()
+
+	client := NewEthClient(srv.URL)
+	blocks := []*Block{new(Block), new(Block), new(Block)}
+	elems := make([]BatchElem, len(blocks))
+	for i := range elems {
+		elems[i] = BatchElem{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{fmt.Sprintf("0x%x", i+1), true},
+			Result: blocks[i],
+		}
+	}
+	if err := client.Batch(context.Background(), elems); err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if elems[i].Error != nil {
+			t.Errorf("elem %d: unexpected error %v", i, elems[i].Error)
+		}
+		if expected := fmt.Sprintf("0x%x", i+1); blocks[i].Number != expected {
+			t.Errorf("elem %d: got block %q, expected %q", i, blocks[i].Number, expected)
+		}
+	}
+	if elems[2].Error == nil {
+		t.Error("expected an error for the unanswered call")
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		is     []error
+	}{
+		{"rpc rate limit", http.StatusOK, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"limit exceeded"}}`, []error{ErrRateLimited}},
+		{"http rate limit", http.StatusTooManyRequests, `too many requests`, []error{ErrRateLimited, ErrHTTPStatus}},
+		{"http error", http.StatusBadGateway, `bad gateway`, []error{ErrHTTPStatus}},
+		{"null result", http.StatusOK, `{"jsonrpc":"2.0","id":1,"result":null}`, []error{ErrNotFound}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+			_, err := client.BlockByNumber(context.Background(), 1)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, target := range tt.is {
+				if !errors.Is(err, target) {
+					t.Errorf("expected %v to match %v", err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestRPCErrorFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"header not found","data":"0x01"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewEthClient(srv.URL).BlockNumber(context.Background())
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("expected *RPCError, got %v", err)
+	}
+	if rpcErr.Code != -32000 || rpcErr.Message != "header not found" || string(rpcErr.Data) != `"0x01"` {
+		t.Errorf("unexpected RPCError %+v", rpcErr)
+	}
+	if errors.Is(err, ErrRateLimited) {
+		t.Error("generic RPC error should not match ErrRateLimited")
+	}
+}
@@ -0,0 +1,50 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+)
+
+// FilterQuery selects logs for eth_getLogs. Either BlockHash or the
+// FromBlock/ToBlock range is used; BlockHash takes precedence.
+type FilterQuery struct {
+	BlockHash string
+	FromBlock int
+	ToBlock   int
+	Addresses []string // emitting contracts, any if empty
+	// Topics restricts each topic position to one of the listed values.
+	// A nil or empty position matches any topic.
+	Topics [][]string
+}
+
+func (q FilterQuery) toArg() map[string]interface{} {
+	arg := make(map[string]interface{})
+	if q.BlockHash != "" {
+		arg["blockHash"] = q.BlockHash
+	} else {
+		arg["fromBlock"] = fmt.Sprintf("0x%x", q.FromBlock)
+		arg["toBlock"] = fmt.Sprintf("0x%x", q.ToBlock)
+	}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+	if len(q.Topics) > 0 {
+		topics := make([]interface{}, len(q.Topics))
+		for i, position := range q.Topics {
+			if len(position) > 0 {
+				topics[i] = position
+			}
+		}
+		arg["topics"] = topics
+	}
+	return arg
+}
+
+// FilterLogs returns the logs matching q using eth_getLogs.
+func (ec *EthClient) FilterLogs(ctx context.Context, q FilterQuery) ([]Log, error) {
+	var logs []Log
+	if err := ec.call(ctx, "eth_getLogs", []interface{}{q.toArg()}, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,238 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Strategy selects how requests are spread over healthy endpoints.
+type Strategy int
+
+const (
+	// Failover sends every request to the first healthy endpoint in the
+	// order they were configured.
+	Failover Strategy = iota
+	// RoundRobin rotates requests over all healthy endpoints.
+	RoundRobin
+)
+
+const (
+	// DefaultMaxLag is how many blocks an endpoint may trail the best known
+	// head before it is taken out of rotation.
+	DefaultMaxLag = 5
+	// failureCooldown keeps an endpoint that failed a request out of
+	// rotation until the next health check or until it expires.
+	failureCooldown = 30 * time.Second
+)
+
+// EndpointStatus is a snapshot of an upstream's health, as reported by
+// EthClient.Endpoints.
+type EndpointStatus struct {
+	URL         string
+	Healthy     bool
+	Head        int // last head reported by eth_blockNumber
+	Lag         int // blocks behind the best head seen across the pool
+	LastError   error
+	LastChecked time.Time
+	Requests    uint64
+	Failures    uint64
+}
+
+type endpoint struct {
+	url      string
+	requests atomic.Uint64
+	failures atomic.Uint64
+
+	mu        sync.Mutex
+	head      int
+	lag       int
+	lastErr   error
+	checked   time.Time
+	downUntil time.Time
+}
+
+func (e *endpoint) available(now time.Time, maxLag int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return now.After(e.downUntil) && e.lag <= maxLag
+}
+
+func (e *endpoint) markFailed(err error) {
+	e.failures.Add(1)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastErr = err
+	e.downUntil = time.Now().Add(failureCooldown)
+}
+
+// endpointPool tracks the configured upstreams and picks one per request.
+type endpointPool struct {
+	endpoints []*endpoint
+	strategy  Strategy
+	maxLag    int
+	next      atomic.Uint64
+}
+
+func newEndpointPool(urls ...string) *endpointPool {
+	p := &endpointPool{maxLag: DefaultMaxLag}
+	for _, url := range urls {
+		p.add(url)
+	}
+	return p
+}
+
+func (p *endpointPool) add(url string) {
+	for _, e := range p.endpoints {
+		if e.url == url {
+			return
+		}
+	}
+	p.endpoints = append(p.endpoints, &endpoint{url: url})
+}
+
+// pick returns the endpoint for the next request. When no endpoint is
+// currently healthy it falls back to rotating over all of them, so a pool
+// that has marked everything down still makes progress.
+func (p *endpointPool) pick() *endpoint {
+	now := time.Now()
+	var healthy []*endpoint
+	for _, e := range p.endpoints {
+		if e.available(now, p.maxLag) {
+			healthy = append(healthy, e)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = p.endpoints
+	}
+	if p.strategy == Failover {
+		return healthy[0]
+	}
+	return healthy[int(p.next.Add(1)-1)%len(healthy)]
+}
+
+// hasAlternative reports whether an endpoint other than e is available.
+func (p *endpointPool) hasAlternative(e *endpoint) bool {
+	now := time.Now()
+	for _, other := range p.endpoints {
+		if other != e && other.available(now, p.maxLag) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithEndpoints adds further upstream URLs to the pool behind the primary
+// URL passed to NewEthClient.
+func WithEndpoints(urls ...string) Option {
+	return func(ec *EthClient) {
+		for _, url := range urls {
+			ec.pool.add(url)
+		}
+	}
+}
+
+// WithStrategy sets how requests are spread over healthy endpoints.
+func WithStrategy(strategy Strategy) Option {
+	return func(ec *EthClient) {
+		ec.pool.strategy = strategy
+	}
+}
+
+// WithMaxLag sets how many blocks an endpoint may trail the best head.
+func WithMaxLag(blocks int) Option {
+	return func(ec *EthClient) {
+		ec.pool.maxLag = blocks
+	}
+}
+
+// CheckEndpoints queries eth_blockNumber on every endpoint concurrently,
+// records the result and takes endpoints that fail or lag the best head by
+// more than the configured threshold out of rotation.
+func (ec *EthClient) CheckEndpoints(ctx context.Context) {
+	endpoints := ec.pool.endpoints
+	heads := make([]int, len(endpoints))
+	errs := make([]error, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, e *endpoint) {
+			defer wg.Done()
+			var result string
+			if errs[i] = ec.callOnce(ctx, e.url, "eth_blockNumber", []string{}, &result); errs[i] != nil {
+				return
+			}
+			head, err := parseQuantity(result)
+			if err != nil {
+				errs[i] = fmt.Errorf("[eth-client] Error parsing response body: %v", err)
+				return
+			}
+			heads[i] = int(head)
+		}(i, e)
+	}
+	wg.Wait()
+
+	best := 0
+	for i := range endpoints {
+		if errs[i] == nil && heads[i] > best {
+			best = heads[i]
+		}
+	}
+
+	now := time.Now()
+	for i, e := range endpoints {
+		e.mu.Lock()
+		e.checked = now
+		e.lastErr = errs[i]
+		if errs[i] != nil {
+			e.downUntil = now.Add(failureCooldown)
+		} else {
+			e.head = heads[i]
+			e.lag = best - heads[i]
+			e.downUntil = time.Time{}
+		}
+		e.mu.Unlock()
+	}
+}
+
+// StartHealthChecks runs CheckEndpoints immediately and then at the given
+// interval until ctx is done.
+func (ec *EthClient) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ec.CheckEndpoints(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Endpoints returns the current status of every configured endpoint.
+func (ec *EthClient) Endpoints() []EndpointStatus {
+	now := time.Now()
+	statuses := make([]EndpointStatus, len(ec.pool.endpoints))
+	for i, e := range ec.pool.endpoints {
+		healthy := e.available(now, ec.pool.maxLag)
+		e.mu.Lock()
+		statuses[i] = EndpointStatus{
+			URL:         e.url,
+			Healthy:     healthy,
+			Head:        e.head,
+			Lag:         e.lag,
+			LastError:   e.lastErr,
+			LastChecked: e.checked,
+			Requests:    e.requests.Load(),
+			Failures:    e.failures.Load(),
+		}
+		e.mu.Unlock()
+	}
+	return statuses
+}
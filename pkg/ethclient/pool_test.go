@@ -0,0 +1,80 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// headNode serves eth_blockNumber with a fixed head and counts requests.
+func headNode(t *testing.T, head int) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"0x%x"}`, head)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestFailoverToHealthyEndpoint(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up, _ := headNode(t, 100)
+
+	client := NewEthClient(down.URL, WithEndpoints(up.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Clock: &fakeClock{}}))
+	number, err := client.BlockNumber(context.Background())
+	if err != nil || number != 100 {
+		t.Fatalf("BlockNumber = %d, %v; expected 100, nil", number, err)
+	}
+
+	statuses := client.Endpoints()
+	if statuses[0].Healthy || statuses[0].Failures != 1 {
+		t.Errorf("failed endpoint should be marked down, got %+v", statuses[0])
+	}
+	if !statuses[1].Healthy {
+		t.Errorf("backup endpoint should be healthy, got %+v", statuses[1])
+	}
+}
+
+func TestHealthCheckDropsLaggingEndpoint(t *testing.T) {
+	behind, behindCalls := headNode(t, 90)
+	ahead, aheadCalls := headNode(t, 100)
+
+	client := NewEthClient(behind.URL, WithEndpoints(ahead.URL), WithMaxLag(5))
+	client.CheckEndpoints(context.Background())
+
+	statuses := client.Endpoints()
+	if statuses[0].Healthy || statuses[0].Lag != 10 {
+		t.Errorf("lagging endpoint should be down with lag 10, got %+v", statuses[0])
+	}
+
+	atomic.StoreInt32(behindCalls, 0)
+	atomic.StoreInt32(aheadCalls, 0)
+	if _, err := client.BlockNumber(context.Background()); err != nil {
+		t.Fatalf("BlockNumber failed: %v", err)
+	}
+	if *behindCalls != 0 || *aheadCalls != 1 {
+		t.Errorf("request should go to the up-to-date endpoint, got behind=%d ahead=%d", *behindCalls, *aheadCalls)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	a, aCalls := headNode(t, 1)
+	b, bCalls := headNode(t, 1)
+
+	client := NewEthClient(a.URL, WithEndpoints(b.URL), WithStrategy(RoundRobin))
+	for i := 0; i < 4; i++ {
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatalf("BlockNumber failed: %v", err)
+		}
+	}
+	if *aCalls != 2 || *bCalls != 2 {
+		t.Errorf("expected requests split 2/2, got %d/%d", *aCalls, *bCalls)
+	}
+}
@@ -0,0 +1,79 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BlockReceipts returns the receipts of every transaction in a block using
+// eth_getBlockReceipts. Older nodes do not implement it; the error then
+// matches ErrMethodNotFound.
+func (ec *EthClient) BlockReceipts(ctx context.Context, blockNumber int) ([]Receipt, error) {
+	var receipts []Receipt
+	if err := ec.call(ctx, "eth_getBlockReceipts", []interface{}{fmt.Sprintf("0x%x", blockNumber)}, &receipts); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// TransactionReceipts fetches the receipts of the given transactions with a
+// single batch of eth_getTransactionReceipt calls. Receipts are returned in
+// the same order as hashes.
+func (ec *EthClient) TransactionReceipts(ctx context.Context, hashes []string) ([]*Receipt, error) {
+	receipts := make([]*Receipt, len(hashes))
+	elems := make([]BatchElem, len(hashes))
+	for i, hash := range hashes {
+		receipts[i] = new(Receipt)
+		elems[i] = BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Params: []interface{}{hash},
+			Result: receipts[i],
+		}
+	}
+	if err := ec.Batch(ctx, elems); err != nil {
+		return nil, err
+	}
+	for i, elem := range elems {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("[eth-client] receipt of %s: %w", hashes[i], elem.Error)
+		}
+	}
+	return receipts, nil
+}
+
+// Receipts returns the receipts for the given transactions of a block,
+// keyed by transaction hash. It prefers eth_getBlockReceipts and falls back
+// to batched eth_getTransactionReceipt calls once the node has shown it
+// does not support the former.
+func (ec *EthClient) Receipts(ctx context.Context, blockNumber int, hashes []string) (map[string]*Receipt, error) {
+	result := make(map[string]*Receipt, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+
+	if !ec.noBlockReceipts.Load() {
+		receipts, err := ec.BlockReceipts(ctx, blockNumber)
+		if err == nil {
+			for i := range receipts {
+				result[receipts[i].TransactionHash] = &receipts[i]
+			}
+			return result, nil
+		}
+		if !errors.Is(err, ErrMethodNotFound) {
+			return nil, err
+		}
+		if ec.noBlockReceipts.CompareAndSwap(false, true) {
+			fmt.Println("[eth-client] eth_getBlockReceipts not supported, falling back to eth_getTransactionReceipt")
+		}
+	}
+
+	receipts, err := ec.TransactionReceipts(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for i, receipt := range receipts {
+		result[hashes[i]] = receipt
+	}
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Clock abstracts waiting between attempts so tests can run retries without
+// real delays.
+type Clock interface {
+	// Sleep blocks for d or until ctx is done, in which case it returns
+	// the context error.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryPolicy controls how failed requests are retried. Delays grow
+// exponentially from BaseDelay up to MaxDelay; a Retry-After header sent by
+// the endpoint takes precedence when it asks for a longer wait.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; <= 1 disables retries
+	BaseDelay   time.Duration // delay before the second attempt
+	MaxDelay    time.Duration // upper bound for the exponential delay
+	Jitter      float64       // fraction of each delay randomised, between 0 and 1
+	Retryable   func(error) bool
+	Clock       Clock
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		Retryable:   IsRetryable,
+		Clock:       realClock{},
+	}
+}
+
+// WithRetryPolicy sets the retry policy. Unset Retryable and Clock fields
+// fall back to IsRetryable and the wall clock.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(ec *EthClient) {
+		if policy.Retryable == nil {
+			policy.Retryable = IsRetryable
+		}
+		if policy.Clock == nil {
+			policy.Clock = realClock{}
+		}
+		ec.retry = policy
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// rate limiting, 5xx responses and network errors. Cancellation and
+// missing data are never retried.
+func IsRetryable(err error) bool {
+	var canceled *CanceledError
+	if err == nil || errors.As(err, &canceled) || errors.Is(err, ErrNotFound) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// withRetry runs attempt against an endpoint picked from the pool until it
+// succeeds, fails with a non-retryable error, the policy runs out of
+// attempts or ctx is done. An endpoint that fails with a retryable error is
+// taken out of rotation; if another endpoint is available the next attempt
+// goes there straight away instead of waiting out the backoff delay.
+func (ec *EthClient) withRetry(ctx context.Context, method string, attempt func(url string) error) error {
+	policy := ec.retry
+	var err error
+	for n := 1; ; n++ {
+		e := ec.pool.pick()
+		e.requests.Add(1)
+		if err = attempt(e.url); err == nil {
+			return nil
+		}
+		retryable := policy.Retryable(err)
+		if retryable {
+			e.markFailed(err)
+		}
+		if n >= policy.MaxAttempts || !retryable {
+			return err
+		}
+		if ec.pool.hasAlternative(e) {
+			continue
+		}
+		if sleepErr := policy.Clock.Sleep(ctx, policy.delay(n, err)); sleepErr != nil {
+			return &CanceledError{Method: method, Err: sleepErr}
+		}
+	}
+}
+
+// delay returns how long to wait after the given failed attempt (1-based).
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > d {
+		d = httpErr.RetryAfter
+	}
+	return d
+}
+
+// parseRetryAfter decodes a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
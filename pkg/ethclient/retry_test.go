@@ -0,0 +1,110 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock records requested sleeps and returns immediately.
+type fakeClock struct {
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	return ctx.Err()
+}
+
+// flakyNode answers with the given failing statuses in turn and then with a
+// successful eth_blockNumber response.
+func flakyNode(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x2a"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryBackoff(t *testing.T) {
+	srv, calls := flakyNode(t, nil, http.StatusServiceUnavailable, http.StatusBadGateway)
+	clock := &fakeClock{}
+	client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+		Clock:       clock,
+	}))
+
+	number, err := client.BlockNumber(context.Background())
+	if err != nil || number != 42 {
+		t.Fatalf("BlockNumber = %d, %v; expected 42, nil", number, err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", *calls)
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if !reflect.DeepEqual(clock.sleeps, expected) {
+		t.Errorf("sleeps = %v, expected %v", clock.sleeps, expected)
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	srv, _ := flakyNode(t, http.Header{"Retry-After": {"3"}}, http.StatusTooManyRequests)
+	clock := &fakeClock{}
+	client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+		Clock:       clock,
+	}))
+
+	if _, err := client.BlockNumber(context.Background()); err != nil {
+		t.Fatalf("BlockNumber failed: %v", err)
+	}
+	if !reflect.DeepEqual(clock.sleeps, []time.Duration{3 * time.Second}) {
+		t.Errorf("sleeps = %v, expected [3s]", clock.sleeps)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, calls := flakyNode(t, nil, http.StatusBadRequest, http.StatusBadRequest)
+	client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Clock: &fakeClock{}}))
+
+	_, err := client.BlockNumber(context.Background())
+	if !errors.Is(err, ErrHTTPStatus) {
+		t.Fatalf("expected HTTP status error, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("non-retryable error was attempted %d times", *calls)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	srv, calls := flakyNode(t, nil, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Clock: &fakeClock{}}))
+
+	_, err := client.BlockNumber(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if *calls > 1 {
+		t.Errorf("expected at most one attempt, got %d", *calls)
+	}
+}
@@ -0,0 +1,234 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoWebSocket is returned when a subscription is requested from a client
+// that was built without WithWebSocketURL.
+var ErrNoWebSocket = errors.New("[eth-client] no websocket endpoint configured")
+
+const (
+	// wsIdleTimeout is how long a subscription may go without any message
+	// before the connection is considered dead and re-established.
+	wsIdleTimeout      = 2 * time.Minute
+	wsReconnectMinWait = time.Second
+	wsReconnectMaxWait = 30 * time.Second
+)
+
+// WithWebSocketURL sets the ws:// or wss:// endpoint used for eth_subscribe.
+func WithWebSocketURL(url string) Option {
+	return func(ec *EthClient) {
+		ec.wsURL = url
+	}
+}
+
+// Subscription is an eth_subscribe subscription that survives dropped
+// connections: when the socket fails it reconnects with exponential backoff
+// and subscribes again. Notifications are delivered until the context
+// passed to Subscribe is done, at which point the channel is closed.
+type Subscription struct {
+	url           string
+	params        []interface{}
+	clock         Clock
+	notifications chan json.RawMessage
+	connected     atomic.Bool
+}
+
+// Subscribe starts an eth_subscribe subscription with the given params, for
+// example "newHeads".
+func (ec *EthClient) Subscribe(ctx context.Context, params ...interface{}) (*Subscription, error) {
+	if ec.wsURL == "" {
+		return nil, ErrNoWebSocket
+	}
+	sub := &Subscription{
+		url:           ec.wsURL,
+		params:        params,
+		clock:         ec.retry.Clock,
+		notifications: make(chan json.RawMessage),
+	}
+	go sub.run(ctx)
+	return sub, nil
+}
+
+// Notifications returns the channel notification payloads are sent on.
+func (s *Subscription) Notifications() <-chan json.RawMessage {
+	return s.notifications
+}
+
+// Connected reports whether the subscription is currently live. While it is
+// false notifications may be missed and callers should fall back to polling.
+func (s *Subscription) Connected() bool {
+	return s.connected.Load()
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.notifications)
+	wait := wsReconnectMinWait
+	for {
+		subscribed, err := s.runOnce(ctx)
+		s.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			wait = wsReconnectMinWait
+		}
+		fmt.Printf("[eth-client] subscription %v disconnected: %v, reconnecting in %s\n", s.params, err, wait)
+		if s.clock.Sleep(ctx, wait) != nil {
+			return
+		}
+		if wait *= 2; wait > wsReconnectMaxWait {
+			wait = wsReconnectMaxWait
+		}
+	}
+}
+
+// subscriptionMessage covers both the eth_subscribe response and the
+// eth_subscription notifications that follow it.
+type subscriptionMessage struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	Method string          `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// runOnce connects, subscribes and forwards notifications until the
+// connection fails. It reports whether the subscription was established.
+func (s *Subscription) runOnce(ctx context.Context) (bool, error) {
+	conn, err := dialWebSocket(ctx, s.url)
+	if err != nil {
+		return false, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		conn.Close()
+	}()
+
+	request := RequestBody{Jsonrpc: "2.0", ID: 1, Method: "eth_subscribe", Params: s.params}
+	if err := conn.WriteJSON(request); err != nil {
+		return false, err
+	}
+
+	var subID string
+	for {
+		conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return subID != "", err
+		}
+		var msg subscriptionMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return subID != "", fmt.Errorf("[eth-client] Error decoding subscription message: %v", err)
+		}
+
+		if subID == "" {
+			if msg.Error != nil {
+				return false, msg.Error
+			}
+			if err := json.Unmarshal(msg.Result, &subID); err != nil || subID == "" {
+				return false, fmt.Errorf("[eth-client] invalid eth_subscribe response: %s", data)
+			}
+			s.connected.Store(true)
+			continue
+		}
+		if msg.Method != "eth_subscription" || msg.Params.Subscription != subID {
+			continue
+		}
+		select {
+		case s.notifications <- msg.Params.Result:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// HeadSubscription delivers decoded newHeads notifications.
+type HeadSubscription struct {
+	*Subscription
+	heads chan *Header
+}
+
+// SubscribeNewHeads subscribes to new chain heads over the client's
+// WebSocket endpoint.
+func (ec *EthClient) SubscribeNewHeads(ctx context.Context) (*HeadSubscription, error) {
+	sub, err := ec.Subscribe(ctx, "newHeads")
+	if err != nil {
+		return nil, err
+	}
+	hs := &HeadSubscription{Subscription: sub, heads: make(chan *Header)}
+	go func() {
+		defer close(hs.heads)
+		for raw := range sub.Notifications() {
+			var header Header
+			if err := json.Unmarshal(raw, &header); err != nil {
+				fmt.Println("[eth-client] Error decoding head: ", err)
+				continue
+			}
+			select {
+			case hs.heads <- &header:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return hs, nil
+}
+
+// Heads returns the channel new heads are sent on. It is closed when the
+// subscription's context is done.
+func (hs *HeadSubscription) Heads() <-chan *Header {
+	return hs.heads
+}
+
+// PendingTxSubscription delivers transactions entering the node's mempool.
+type PendingTxSubscription struct {
+	*Subscription
+	txs chan *Transaction
+}
+
+// SubscribePendingTransactions subscribes to newPendingTransactions asking
+// for full transaction bodies. Nodes that only send hashes deliver
+// transactions with just Hash set; use TransactionByHash to fetch the rest.
+func (ec *EthClient) SubscribePendingTransactions(ctx context.Context) (*PendingTxSubscription, error) {
+	sub, err := ec.Subscribe(ctx, "newPendingTransactions", true)
+	if err != nil {
+		return nil, err
+	}
+	ps := &PendingTxSubscription{Subscription: sub, txs: make(chan *Transaction)}
+	go func() {
+		defer close(ps.txs)
+		for raw := range sub.Notifications() {
+			tx := new(Transaction)
+			if err := json.Unmarshal(raw, &tx.Hash); err != nil {
+				if err := json.Unmarshal(raw, tx); err != nil {
+					fmt.Println("[eth-client] Error decoding pending transaction: ", err)
+					continue
+				}
+			}
+			select {
+			case ps.txs <- tx:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ps, nil
+}
+
+// Transactions returns the channel pending transactions are sent on. It is
+// closed when the subscription's context is done.
+func (ps *PendingTxSubscription) Transactions() <-chan *Transaction {
+	return ps.txs
+}
@@ -0,0 +1,82 @@
+package ethclient
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsNode accepts WebSocket connections and, on each one, confirms the
+// eth_subscribe call, sends a single newHeads notification for the next
+// block number and then drops the connection.
+func wsNode(t *testing.T) *httptest.Server {
+	block := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")))
+		rw.Flush()
+
+		// The client reader accepts masked frames, so wsConn can play the
+		// server side as well.
+		ws := &wsConn{conn: conn, br: bufio.NewReader(rw)}
+		msg, err := ws.ReadMessage()
+		if err != nil || !strings.Contains(string(msg), "newHeads") {
+			t.Errorf("expected newHeads subscribe, got %s, %v", msg, err)
+			return
+		}
+		block++
+		ws.writeFrame(wsText, []byte(`{"jsonrpc":"2.0","id":1,"result":"0xsub"}`))
+		ws.writeFrame(wsText, []byte(fmt.Sprintf(
+			`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xsub","result":{"number":"0x%x","hash":"0x%02x"}}}`, block, block)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSubscribeNewHeadsReconnects(t *testing.T) {
+	srv := wsNode(t)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	client := NewEthClient(srv.URL, WithWebSocketURL(wsURL), WithRetryPolicy(RetryPolicy{Clock: &fakeClock{}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := client.SubscribeNewHeads(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeNewHeads failed: %v", err)
+	}
+
+	for _, expected := range []string{"0x1", "0x2"} {
+		select {
+		case head := <-sub.Heads():
+			if head.Number != expected {
+				t.Errorf("got head %s, expected %s", head.Number, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for head %s", expected)
+		}
+	}
+
+	cancel()
+	for range sub.Heads() {
+	}
+	if sub.Connected() {
+		t.Error("subscription should report disconnected after cancel")
+	}
+}
+
+func TestSubscribeWithoutWebSocket(t *testing.T) {
+	if _, err := NewEthClient("http://localhost").SubscribeNewHeads(context.Background()); err != ErrNoWebSocket {
+		t.Errorf("expected ErrNoWebSocket, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tracing methods, in the order InternalCalls tries them.
+const (
+	tracerUnknown int32 = iota
+	tracerDebug         // debug_traceBlockByNumber with the callTracer
+	tracerParity        // trace_block
+	tracerNone
+)
+
+// CallFrame is a call as reported by geth's callTracer.
+type CallFrame struct {
+	Type    string      `json:"type"`
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Value   string      `json:"value"`
+	Gas     string      `json:"gas"`
+	GasUsed string      `json:"gasUsed"`
+	Input   string      `json:"input"`
+	Output  string      `json:"output"`
+	Error   string      `json:"error"`
+	Calls   []CallFrame `json:"calls"`
+}
+
+// TxTrace is the callTracer result for one transaction of a block.
+type TxTrace struct {
+	TxHash string    `json:"txHash"`
+	Result CallFrame `json:"result"`
+}
+
+// ParityTrace is an entry of the flat list returned by trace_block.
+type ParityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType      string `json:"callType"`
+		From          string `json:"from"`
+		To            string `json:"to"`
+		Value         string `json:"value"`
+		Address       string `json:"address"`       // selfdestructed contract
+		RefundAddress string `json:"refundAddress"` // selfdestruct beneficiary
+		Balance       string `json:"balance"`       // selfdestructed balance
+	} `json:"action"`
+	Result *struct {
+		Address string `json:"address"` // created contract
+	} `json:"result"`
+	Error           string `json:"error"`
+	TraceAddress    []int  `json:"traceAddress"`
+	TransactionHash string `json:"transactionHash"`
+}
+
+// InternalCall is a value transfer or contract creation made by a contract
+// while executing a transaction, i.e. a nested call frame that moved ETH or
+// deployed a contract.
+type InternalCall struct {
+	TxHash string
+	Type   string // call, create or selfdestruct
+	From   string
+	To     string
+	Value  string // hex quantity
+	// TraceAddress is the path of the frame in the call tree, e.g. "0-2"
+	// for the third call made by the first call of the transaction.
+	TraceAddress string
+}
+
+// TraceBlockCalls traces every transaction in a block with
+// debug_traceBlockByNumber and geth's callTracer.
+func (ec *EthClient) TraceBlockCalls(ctx context.Context, blockNumber int) ([]TxTrace, error) {
+	var traces []TxTrace
+	params := []interface{}{fmt.Sprintf("0x%x", blockNumber), map[string]string{"tracer": "callTracer"}}
+	if err := ec.call(ctx, "debug_traceBlockByNumber", params, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// TraceBlock returns the flat call traces of a block with trace_block, as
+// implemented by Erigon, Nethermind and OpenEthereum.
+func (ec *EthClient) TraceBlock(ctx context.Context, blockNumber int) ([]ParityTrace, error) {
+	var traces []ParityTrace
+	if err := ec.call(ctx, "trace_block", []interface{}{fmt.Sprintf("0x%x", blockNumber)}, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// InternalCalls returns the value transfers made and contracts created by
+// contracts in a block, excluding the top-level transactions and anything
+// reverted. It uses debug_traceBlockByNumber or, if the node lacks it,
+// trace_block, and remembers which one works. If the node supports neither,
+// the error matches ErrTracingUnsupported. txHashes, the block's
+// transactions in order, name the traces of nodes that omit the hash from
+// callTracer results.
+func (ec *EthClient) InternalCalls(ctx context.Context, blockNumber int, txHashes []string) ([]InternalCall, error) {
+	for {
+		switch ec.tracer.Load() {
+		case tracerUnknown, tracerDebug:
+			traces, err := ec.TraceBlockCalls(ctx, blockNumber)
+			if errors.Is(err, ErrMethodNotFound) {
+				ec.unsupported("debug_traceBlockByNumber", tracerParity)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			ec.tracer.CompareAndSwap(tracerUnknown, tracerDebug)
+			var calls []InternalCall
+			for i, trace := range traces {
+				if trace.Result.Error != "" {
+					continue
+				}
+				hash := trace.TxHash
+				if hash == "" && i < len(txHashes) {
+					hash = txHashes[i]
+				}
+				calls = flattenFrames(calls, hash, trace.Result.Calls, "")
+			}
+			return calls, nil
+
+		case tracerParity:
+			traces, err := ec.TraceBlock(ctx, blockNumber)
+			if errors.Is(err, ErrMethodNotFound) {
+				ec.unsupported("trace_block", tracerNone)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			return flattenParity(traces), nil
+
+		default:
+			return nil, ErrTracingUnsupported
+		}
+	}
+}
+
+// unsupported moves on to the next tracing method once the node has
+// rejected one, logging it only the first time.
+func (ec *EthClient) unsupported(method string, next int32) {
+	for {
+		current := ec.tracer.Load()
+		if current >= next {
+			return
+		}
+		if ec.tracer.CompareAndSwap(current, next) {
+			fmt.Printf("[eth-client] %s not supported\n", method)
+			return
+		}
+	}
+}
+
+// flattenFrames appends the frames in frames and their children that moved
+// value or created a contract to calls. Reverted frames are skipped together
+// with their children, whose effects were undone as well.
+func flattenFrames(calls []InternalCall, txHash string, frames []CallFrame, parent string) []InternalCall {
+	for i, frame := range frames {
+		if frame.Error != "" {
+			continue
+		}
+		address := strconv.Itoa(i)
+		if parent != "" {
+			address = parent + "-" + address
+		}
+		kind := strings.ToLower(frame.Type)
+		switch kind {
+		case "call", "create", "create2", "selfdestruct":
+			if hasValue(frame.Value) || kind == "create" || kind == "create2" {
+				if kind == "create2" {
+					kind = "create"
+				}
+				calls = append(calls, InternalCall{
+					TxHash:       txHash,
+					Type:         kind,
+					From:         frame.From,
+					To:           frame.To,
+					Value:        frame.Value,
+					TraceAddress: address,
+				})
+			}
+		}
+		calls = flattenFrames(calls, txHash, frame.Calls, address)
+	}
+	return calls
+}
+
+// flattenParity extracts the nested traces that moved value or created a
+// contract from a trace_block result, skipping reverted traces and their
+// descendants.
+func flattenParity(traces []ParityTrace) []InternalCall {
+	var calls []InternalCall
+	reverted := make(map[string]bool)
+	for _, trace := range traces {
+		path := make([]string, len(trace.TraceAddress))
+		for i, n := range trace.TraceAddress {
+			path[i] = strconv.Itoa(n)
+		}
+		address := strings.Join(path, "-")
+		if trace.Error != "" {
+			reverted[trace.TransactionHash+":"+address] = true
+		}
+		if len(path) == 0 || trace.Error != "" || isReverted(reverted, trace.TransactionHash, path) {
+			continue
+		}
+
+		call := InternalCall{TxHash: trace.TransactionHash, TraceAddress: address}
+		switch trace.Type {
+		case "call":
+			if trace.Action.CallType == "delegatecall" || trace.Action.CallType == "staticcall" {
+				continue
+			}
+			call.Type, call.From, call.To, call.Value = "call", trace.Action.From, trace.Action.To, trace.Action.Value
+		case "create":
+			call.Type, call.From, call.Value = "create", trace.Action.From, trace.Action.Value
+			if trace.Result != nil {
+				call.To = trace.Result.Address
+			}
+		case "suicide":
+			call.Type, call.From, call.To, call.Value = "selfdestruct", trace.Action.Address, trace.Action.RefundAddress, trace.Action.Balance
+		default:
+			continue
+		}
+		if hasValue(call.Value) || call.Type == "create" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// isReverted reports whether an ancestor of the trace at path reverted.
+func isReverted(reverted map[string]bool, txHash string, path []string) bool {
+	for i := 0; i < len(path); i++ {
+		if reverted[txHash+":"+strings.Join(path[:i], "-")] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasValue(value string) bool {
+	return strings.TrimLeft(strings.TrimPrefix(value, "0x"), "0") != ""
+}
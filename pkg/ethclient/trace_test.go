@@ -0,0 +1,66 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const parityTraces = `[
+	{"type":"call","action":{"callType":"call","from":"0xa","to":"0xc","value":"0x5"},"traceAddress":[],"transactionHash":"0x1"},
+	{"type":"call","action":{"callType":"call","from":"0xc","to":"0xb","value":"0x3"},"traceAddress":[0],"transactionHash":"0x1"},
+	{"type":"call","action":{"callType":"delegatecall","from":"0xc","to":"0xd","value":"0x5"},"traceAddress":[1],"transactionHash":"0x1"},
+	{"type":"call","action":{"callType":"call","from":"0xc","to":"0xe","value":"0x1"},"traceAddress":[2],"error":"Reverted","transactionHash":"0x1"},
+	{"type":"call","action":{"callType":"call","from":"0xe","to":"0xf","value":"0x1"},"traceAddress":[2,0],"transactionHash":"0x1"},
+	{"type":"suicide","action":{"address":"0xc","refundAddress":"0xb","balance":"0x2"},"traceAddress":[3],"transactionHash":"0x1"}
+]`
+
+func TestInternalCallsFallsBackToTraceBlock(t *testing.T) {
+	var debugCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "debug_traceBlockByNumber":
+			debugCalls.Add(1)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"the method debug_traceBlockByNumber does not exist/is not available"}}`))
+		case "trace_block":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + parityTraces + `}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	for i := 0; i < 2; i++ {
+		calls, err := client.InternalCalls(context.Background(), 1, nil)
+		if err != nil {
+			t.Fatalf("InternalCalls failed: %v", err)
+		}
+		// The top-level call, the delegatecall and the reverted subtree
+		// are left out.
+		if len(calls) != 2 ||
+			calls[0] != (InternalCall{TxHash: "0x1", Type: "call", From: "0xc", To: "0xb", Value: "0x3", TraceAddress: "0"}) ||
+			calls[1] != (InternalCall{TxHash: "0x1", Type: "selfdestruct", From: "0xc", To: "0xb", Value: "0x2", TraceAddress: "3"}) {
+			t.Errorf("unexpected internal calls %+v", calls)
+		}
+	}
+	if n := debugCalls.Load(); n != 1 {
+		t.Errorf("debug_traceBlockByNumber called %d times, expected once", n)
+	}
+}
+
+func TestInternalCallsUnsupported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewEthClient(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if _, err := client.InternalCalls(context.Background(), 1, nil); !errors.Is(err, ErrTracingUnsupported) {
+		t.Errorf("expected ErrTracingUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package ethclient
+
+import "context"
+
+// TxPoolContent is the result of txpool_content: transactions by sender and
+// nonce. Pending transactions are executable now, queued ones wait for a
+// nonce gap to be filled.
+type TxPoolContent struct {
+	Pending map[string]map[string]*Transaction `json:"pending"`
+	Queued  map[string]map[string]*Transaction `json:"queued"`
+}
+
+// TxPoolContent returns the node's mempool. The method is not part of the
+// standard API; nodes that lack it return an error matching
+// ErrMethodNotFound.
+func (ec *EthClient) TxPoolContent(ctx context.Context) (*TxPoolContent, error) {
+	var content TxPoolContent
+	if err := ec.call(ctx, "txpool_content", []interface{}{}, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// TransactionByHash returns a transaction, pending or mined, by hash. The
+// error matches ErrNotFound if the node does not know it.
+func (ec *EthClient) TransactionByHash(ctx context.Context, hash string) (*Transaction, error) {
+	var tx Transaction
+	if err := ec.call(ctx, "eth_getTransactionByHash", []interface{}{hash}, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
@@ -1,5 +1,7 @@
 package ethclient
 
+import "encoding/json"
+
 type AccessListEntry struct {
 	Address     string   `json:"address"`
 	StorageKeys []string `json:"storageKeys"`
@@ -7,39 +9,130 @@ type AccessListEntry struct {
 
 type RequestBody struct {
 	Jsonrpc string      `json:"jsonrpc"`
-	ID      int         `json:"id"`
+	ID      uint64      `json:"id"`
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params"`
 }
 
 type JSONRPCResponse struct {
-	Jsonrpc string `json:"jsonrpc"`
-	ID      int    `json:"id"`
-	Result  Block  `json:"result"`
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error,omitempty"`
 }
 
 type Block struct {
-	Number       string        `json:"number"`
-	Hash         string        `json:"hash"`
-	Transactions []Transaction `json:"transactions"`
+	Number        string        `json:"number"`
+	Hash          string        `json:"hash"`
+	ParentHash    string        `json:"parentHash"`
+	Timestamp     string        `json:"timestamp"`
+	Miner         string        `json:"miner"` // fee recipient
+	GasUsed       string        `json:"gasUsed"`
+	GasLimit      string        `json:"gasLimit"`
+	BaseFeePerGas string        `json:"baseFeePerGas"` // empty before the London fork
+	Transactions  []Transaction `json:"transactions"`
+
+	// Withdrawals are beacon chain withdrawals, from the Shanghai fork on.
+	Withdrawals     []Withdrawal `json:"withdrawals"`
+	WithdrawalsRoot string       `json:"withdrawalsRoot"`
+	// BlobGasUsed and ExcessBlobGas are set from the Cancun fork on.
+	BlobGasUsed   string `json:"blobGasUsed"`
+	ExcessBlobGas string `json:"excessBlobGas"`
+}
+
+// Withdrawal is a validator withdrawal processed in a block. Amount is in
+// gwei.
+type Withdrawal struct {
+	Index          string `json:"index"`
+	ValidatorIndex string `json:"validatorIndex"`
+	Address        string `json:"address"`
+	Amount         string `json:"amount"`
 }
 
+// Header is the block header delivered by a newHeads subscription and
+// returned by HeaderByTag.
+type Header struct {
+	Number     string `json:"number"`
+	Hash       string `json:"hash"`
+	ParentHash string `json:"parentHash"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// Transaction is a transaction of any type as returned by the node. Fields
+// that do not apply to a transaction's type are empty.
 type Transaction struct {
-	ChainID          string            `json:"chainId"`
-	BlockNumber      string            `json:"blockNumber"`
-	BlockHash        string            `json:"-"`
-	Hash             string            `json:"hash"`
-	Nonce            string            `json:"nonce"`
-	From             string            `json:"from"`
-	To               string            `json:"to"`
-	Value            string            `json:"value"`
-	Gas              string            `json:"gas"`
-	GasPrice         string            `json:"gasPrice"`
-	Input            string            `json:"input"`
-	Type             string            `json:"-"`
-	R                string            `json:"-"`
-	S                string            `json:"-"`
-	V                string            `json:"-"`
-	TransactionIndex string            `json:"-"`
-	AccessList       []AccessListEntry `json:"-"`
+	ChainID          string `json:"chainId"`
+	BlockNumber      string `json:"blockNumber"`
+	BlockHash        string `json:"blockHash"`
+	Hash             string `json:"hash"`
+	Nonce            string `json:"nonce"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Value            string `json:"value"`
+	Gas              string `json:"gas"`
+	GasPrice         string `json:"gasPrice"`
+	Input            string `json:"input"`
+	Type             string `json:"type"`
+	TransactionIndex string `json:"transactionIndex"`
+
+	// EIP-2930 and later.
+	AccessList []AccessListEntry `json:"accessList"`
+	// EIP-1559 and later.
+	MaxFeePerGas         string `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas"`
+	// EIP-4844.
+	MaxFeePerBlobGas    string   `json:"maxFeePerBlobGas"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes"`
+	// EIP-7702.
+	AuthorizationList []Authorization `json:"authorizationList"`
+
+	// Signature. Typed transactions report yParity, which nodes usually
+	// repeat as v.
+	V       string `json:"v"`
+	R       string `json:"r"`
+	S       string `json:"s"`
+	YParity string `json:"yParity"`
+}
+
+// Authorization is an EIP-7702 authorization to set the code of the
+// signing account to that of Address.
+type Authorization struct {
+	ChainID string `json:"chainId"`
+	Address string `json:"address"`
+	Nonce   string `json:"nonce"`
+	YParity string `json:"yParity"`
+	R       string `json:"r"`
+	S       string `json:"s"`
+}
+
+// Receipt is the result of eth_getTransactionReceipt and, as a list, of
+// eth_getBlockReceipts.
+type Receipt struct {
+	TransactionHash   string `json:"transactionHash"`
+	TransactionIndex  string `json:"transactionIndex"`
+	BlockHash         string `json:"blockHash"`
+	BlockNumber       string `json:"blockNumber"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	Status            string `json:"status"`
+	GasUsed           string `json:"gasUsed"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	ContractAddress   string `json:"contractAddress"`
+	Type              string `json:"type"`
+	Logs              []Log  `json:"logs"`
+}
+
+// Log is an event emitted by a contract, as found in receipts and returned
+// by eth_getLogs.
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
 }